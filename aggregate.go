@@ -0,0 +1,208 @@
+package json2csv
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/yukithm/json2csv/expr"
+)
+
+// AggFunc is an aggregate function.
+type AggFunc uint
+
+// Aggregate functions
+const (
+	// The number of rows, or non-null values of the column.
+	CountAgg AggFunc = iota
+
+	// The exact sum and average of numbers. Other values are ignored.
+	SumAgg
+	AvgAgg
+
+	// The minimum and maximum values compared in the same way as SortResults.
+	MinAgg
+	MaxAgg
+)
+
+var aggFuncNames = map[AggFunc]string{
+	CountAgg: "count",
+	SumAgg:   "sum",
+	AvgAgg:   "avg",
+	MinAgg:   "min",
+	MaxAgg:   "max",
+}
+
+func (f AggFunc) String() string {
+	return aggFuncNames[f]
+}
+
+// Aggregation is an aggregate function applied to the column.
+type Aggregation struct {
+	Func AggFunc
+
+	// Key is the JSON Pointer of the column. It is empty for count of rows.
+	Key string
+}
+
+// ResultKey returns the key of the aggregated value, which is the key
+// prefixed with the function name. (e.g. "/sum/amount", "/count")
+func (a Aggregation) ResultKey() string {
+	return "/" + a.Func.String() + a.Key
+}
+
+// Aggregate groups the results by the values of the keys (JSON Pointers) and
+// returns a result per group in the order of appearance. Each result has the
+// values of the keys and the aggregated values at ResultKey. Missing and
+// null values of the keys form their own groups.
+//
+// Average of numbers which cannot be written as exact decimals is rounded to
+// 16 digits after the point.
+func Aggregate(results []KeyValue, keys []string, aggs []Aggregation) []KeyValue {
+	type digest [sha256.Size]byte
+	index := make(map[digest]int)
+	var groups []*aggGroup
+
+	h := sha256.New()
+	for _, result := range results {
+		h.Reset()
+		hashKeys(h, result, keys)
+		var d digest
+		h.Sum(d[:0])
+
+		i, ok := index[d]
+		if !ok {
+			i = len(groups)
+			index[d] = i
+			groups = append(groups, newAggGroup(result, keys, len(aggs)))
+		}
+		groups[i].add(result, aggs)
+	}
+
+	aggregated := make([]KeyValue, 0, len(groups))
+	for _, g := range groups {
+		aggregated = append(aggregated, g.result(aggs))
+	}
+	return aggregated
+}
+
+type aggGroup struct {
+	keys   KeyValue
+	states []aggState
+}
+
+// aggState is the state of an aggregation in a group.
+type aggState struct {
+	count int
+	sum   *big.Rat
+	best  sortValue
+	value interface{} // original value of best
+}
+
+func newAggGroup(result KeyValue, keys []string, n int) *aggGroup {
+	g := &aggGroup{KeyValue{}, make([]aggState, n)}
+	for _, key := range keys {
+		if value, ok := result[key]; ok {
+			g.keys[key] = value
+		}
+	}
+	return g
+}
+
+func (g *aggGroup) add(result KeyValue, aggs []Aggregation) {
+	for i, agg := range aggs {
+		state := &g.states[i]
+		if agg.Func == CountAgg && agg.Key == "" {
+			state.count++
+			continue
+		}
+
+		value, ok := result[agg.Key]
+		if !ok || value == nil {
+			continue
+		}
+		switch agg.Func {
+		case CountAgg:
+			state.count++
+		case SumAgg, AvgAgg:
+			v := sortValueOf(result, agg.Key)
+			if v.number == nil {
+				continue
+			}
+			if state.sum == nil {
+				state.sum = new(big.Rat)
+			}
+			state.sum.Add(state.sum, v.number)
+			state.count++
+		case MinAgg, MaxAgg:
+			v := sortValueOf(result, agg.Key)
+			if v.missing() {
+				continue
+			}
+			c := v.compare(state.best)
+			if state.best.missing() || (agg.Func == MinAgg && c < 0) || (agg.Func == MaxAgg && c > 0) {
+				state.best, state.value = v, value
+			}
+		}
+	}
+}
+
+func (g *aggGroup) result(aggs []Aggregation) KeyValue {
+	result := make(KeyValue, len(g.keys)+len(aggs))
+	for key, value := range g.keys {
+		result[key] = value
+	}
+	for i, agg := range aggs {
+		state := g.states[i]
+		var value interface{}
+		switch agg.Func {
+		case CountAgg:
+			value = int64(state.count)
+		case SumAgg:
+			if state.sum != nil {
+				value = json.Number(expr.FormatDecimal(state.sum))
+			}
+		case AvgAgg:
+			if state.sum != nil {
+				avg := new(big.Rat).Quo(state.sum, big.NewRat(int64(state.count), 1))
+				value = json.Number(expr.FormatDecimal(avg))
+			}
+		case MinAgg, MaxAgg:
+			value = state.value
+		}
+		result[agg.ResultKey()] = value
+	}
+	return result
+}
+
+// AggregationKeys returns the keys of results of Aggregate in the order of
+// the group keys and the aggregations.
+func AggregationKeys(keys []string, aggs []Aggregation) []string {
+	columns := append([]string{}, keys...)
+	for _, agg := range aggs {
+		columns = append(columns, agg.ResultKey())
+	}
+	return columns
+}
+
+// CheckAggregationKeys returns an error if keys of results of Aggregate
+// collide, e.g. "sum(/a)" given twice, or the group key "/count" with
+// "count()".
+func CheckAggregationKeys(keys []string, aggs []Aggregation) error {
+	seen := make(map[string]bool, len(keys)+len(aggs))
+	for _, key := range keys {
+		if seen[key] {
+			return fmt.Errorf("Duplicate group column %s", key)
+		}
+		seen[key] = true
+	}
+	for _, agg := range aggs {
+		key := agg.ResultKey()
+		if seen[key] {
+			return fmt.Errorf("Aggregated column %s collides with another column", key)
+		}
+		seen[key] = true
+	}
+	return nil
+}
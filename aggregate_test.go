@@ -0,0 +1,101 @@
+package json2csv
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestAggregate(t *testing.T) {
+	obj, err := json2obj(`[
+		{"country": "JP", "city": "Tokyo", "amount": 0.1, "score": 3, "ts": "2024-01-02"},
+		{"country": "JP", "city": "Osaka", "amount": 0.2, "score": 4, "ts": "2024-03-01"},
+		{"country": "US", "city": "NYC", "amount": "n/a", "score": null, "ts": "2023-01-01"},
+		{"country": "JP", "city": "Tokyo", "amount": 1e20, "score": 4},
+		{"city": "?", "amount": 1}
+	]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := JSON2CSV(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	aggs := []Aggregation{
+		{CountAgg, ""},
+		{CountAgg, "/score"},
+		{SumAgg, "/amount"},
+		{AvgAgg, "/score"},
+		{MinAgg, "/ts"},
+		{MaxAgg, "/amount"},
+	}
+	expected := []KeyValue{
+		{
+			"/country":     "JP",
+			"/count":       int64(3),
+			"/count/score": int64(3),
+			"/sum/amount":  json.Number("100000000000000000000.3"),
+			"/avg/score":   json.Number("3.6666666666666667"),
+			"/min/ts":      "2024-01-02",
+			"/max/amount":  json.Number("1e20"),
+		},
+		{
+			"/country":     "US",
+			"/count":       int64(1),
+			"/count/score": int64(0),
+			"/sum/amount":  nil,
+			"/avg/score":   nil,
+			"/min/ts":      "2023-01-01",
+			"/max/amount":  "n/a",
+		},
+		{
+			"/count":       int64(1),
+			"/count/score": int64(0),
+			"/sum/amount":  json.Number("1"),
+			"/avg/score":   nil,
+			"/min/ts":      nil,
+			"/max/amount":  json.Number("1"),
+		},
+	}
+
+	actual := Aggregate(results, []string{"/country"}, aggs)
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Expected %#v, but %#v", expected, actual)
+	}
+
+	actual = Aggregate(results, []string{"/country", "/city"}, []Aggregation{{CountAgg, ""}})
+	if len(actual) != 4 || actual[0]["/city"] != "Tokyo" || actual[0]["/count"] != int64(2) {
+		t.Errorf("Expected 4 groups, but %#v", actual)
+	}
+
+	actual = Aggregate(results, nil, []Aggregation{{CountAgg, ""}})
+	if !reflect.DeepEqual([]KeyValue{{"/count": int64(5)}}, actual) {
+		t.Errorf("Expected a group, but %#v", actual)
+	}
+
+	keys := AggregationKeys([]string{"/country"}, aggs[:3])
+	if !reflect.DeepEqual([]string{"/country", "/count", "/count/score", "/sum/amount"}, keys) {
+		t.Errorf("Unexpected keys %v", keys)
+	}
+}
+
+func TestCheckAggregationKeys(t *testing.T) {
+	testCases := []struct {
+		keys []string
+		aggs []Aggregation
+		ok   bool
+	}{
+		{[]string{"/country"}, []Aggregation{{CountAgg, ""}, {SumAgg, "/amount"}, {AvgAgg, "/amount"}}, true},
+		{nil, []Aggregation{{SumAgg, "/a"}, {SumAgg, "/a"}}, false},
+		{[]string{"/count"}, []Aggregation{{CountAgg, ""}}, false},
+		{[]string{"/sum/a"}, []Aggregation{{SumAgg, "/a"}}, false},
+		{[]string{"/a", "/a"}, nil, false},
+	}
+	for caseIndex, testCase := range testCases {
+		err := CheckAggregationKeys(testCase.keys, testCase.aggs)
+		if (err == nil) != testCase.ok {
+			t.Errorf("%d: Expected ok=%v, but %v", caseIndex, testCase.ok, err)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/yukithm/json2csv"
+	"github.com/yukithm/json2csv/jsonpointer"
+
+	"github.com/urfave/cli"
+)
+
+var aggFuncTable = map[string]json2csv.AggFunc{
+	"count": json2csv.CountAgg,
+	"sum":   json2csv.SumAgg,
+	"avg":   json2csv.AvgAgg,
+	"min":   json2csv.MinAgg,
+	"max":   json2csv.MaxAgg,
+}
+
+// aggregateInputFlags are global options read by readInputs and
+// convertInputs, which the aggregate command takes as its own options.
+var aggregateInputFlags = []string{
+	"path",
+	"input-format",
+	"binary-encoding",
+	"where",
+	"add-column",
+	"source-column",
+	"validate",
+	"reject-file",
+}
+
+var aggPattern = regexp.MustCompile(`^(\w+)\(\s*([^()]*?)\s*\)$`)
+
+// parseAggregations parses --agg value, comma separated functions such as
+// "count(),sum(/amount)". Only count() can omit the JSON Pointer.
+func parseAggregations(spec string) ([]json2csv.Aggregation, error) {
+	var aggs []json2csv.Aggregation
+	for _, s := range splitAggregations(spec) {
+		s = strings.TrimSpace(s)
+		m := aggPattern.FindStringSubmatch(s)
+		if m == nil {
+			return nil, fmt.Errorf("Invalid --agg value %q", s)
+		}
+		f, ok := aggFuncTable[m[1]]
+		if !ok {
+			return nil, fmt.Errorf("Unknown aggregate function %q", m[1])
+		}
+		if _, err := jsonpointer.New(m[2]); err != nil || (m[2] == "" && f != json2csv.CountAgg) {
+			return nil, fmt.Errorf("Invalid --agg value %q", s)
+		}
+		aggs = append(aggs, json2csv.Aggregation{Func: f, Key: m[2]})
+	}
+	return aggs, nil
+}
+
+// splitAggregations splits the value by commas outside parentheses.
+func splitAggregations(spec string) []string {
+	var list []string
+	depth, start := 0, 0
+	for i, r := range spec {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				list = append(list, spec[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(list, spec[start:])
+}
+
+// aggregateAction writes a row per group. Rows are deduplicated before
+// aggregation, and the output is controlled by the global options.
+func aggregateAction(c *cli.Context) {
+	inputs, err := readInputs(c)
+	if err != nil {
+		log.Fatal(err)
+	}
+	results, err := convertInputs(c, inputs, false)
+	if err != nil {
+		log.Fatal(err)
+	}
+	results = dedupeResults(c.Parent(), results)
+
+	// validated in Before
+	keys, _ := parsePointerList("group-by", c.String("group-by"))
+	aggs, _ := parseAggregations(c.String("agg"))
+	aggregated := json2csv.Aggregate(results, keys, aggs)
+	if len(aggregated) == 0 {
+		return
+	}
+	outputResults(c.Parent(), aggregated, json2csv.AggregationKeys(keys, aggs))
+}
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// binary encodings for --binary-encoding
+const (
+	base64Encoding = "base64"
+	hexEncoding    = "hex"
+)
+
+var binaryEncodingTable = map[string]bool{
+	base64Encoding: true,
+	hexEncoding:    true,
+}
+
+// readMessagePack decodes MessagePack. A sequence of values is returned as an
+// array.
+func readMessagePack(r io.Reader) (interface{}, error) {
+	br := bufio.NewReader(r)
+	decoder := msgpack.NewDecoder(br)
+	// Maps may have non-string keys.
+	decoder.SetMapDecoder(func(d *msgpack.Decoder) (interface{}, error) {
+		return d.DecodeUntypedMap()
+	})
+	return readSequence(func() (interface{}, error) {
+		if _, err := br.Peek(1); err != nil {
+			return nil, err
+		}
+		v, err := decoder.DecodeInterface()
+		if err == io.EOF {
+			// The value is truncated.
+			err = io.ErrUnexpectedEOF
+		}
+		return v, err
+	})
+}
+
+var cborDecMode = func() cbor.DecMode {
+	mode, err := cbor.DecOptions{
+		TimeTag:     cbor.DecTagOptional,
+		BigIntDec:   cbor.BigIntDecodePointer,
+		MaxMapPairs: 1 << 30,
+	}.DecMode()
+	if err != nil {
+		panic(err)
+	}
+	return mode
+}()
+
+// readCBOR decodes CBOR. A CBOR sequence is returned as an array.
+func readCBOR(r io.Reader) (interface{}, error) {
+	// The decoder reads ahead into its own buffer, and returns io.EOF at the
+	// end of the sequence.
+	decoder := cborDecMode.NewDecoder(r)
+	return readSequence(func() (interface{}, error) {
+		var v interface{}
+		err := decoder.Decode(&v)
+		return v, err
+	})
+}
+
+// readBSON decodes BSON documents such as the output of mongodump.
+// Multiple documents are returned as an array.
+func readBSON(r io.Reader) (interface{}, error) {
+	br := bufio.NewReader(r)
+	return readSequence(func() (interface{}, error) {
+		if _, err := br.Peek(1); err != nil {
+			return nil, err
+		}
+		head, err := br.Peek(4)
+		if err != nil {
+			return nil, io.ErrUnexpectedEOF
+		}
+		size := int(binary.LittleEndian.Uint32(head))
+		if size < 5 {
+			return nil, fmt.Errorf("invalid BSON document size %d", size)
+		}
+		doc := make([]byte, size)
+		if _, err := io.ReadFull(br, doc); err != nil {
+			return nil, err
+		}
+		var d bson.D
+		if err := bson.Unmarshal(doc, &d); err != nil {
+			return nil, err
+		}
+		return d, nil
+	})
+}
+
+// readSequence decodes values until decode returns io.EOF and converts them
+// into the same structure as JSON decoded with UseNumber option.
+func readSequence(decode func() (interface{}, error)) (interface{}, error) {
+	var values []interface{}
+	for {
+		v, err := decode()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		value, err := binaryValue(v)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+
+	switch len(values) {
+	case 0:
+		return nil, io.EOF
+	case 1:
+		return values[0], nil
+	default:
+		return values, nil
+	}
+}
+
+// binaryValue converts a value decoded from MessagePack, CBOR or BSON into
+// a JSON value. Numbers become json.Number, and types without JSON
+// counterpart become strings, except []byte and time.Time which are left to
+// encodeBinary.
+func binaryValue(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case nil, bool, string, []byte:
+		return v, nil
+	case time.Time:
+		// Timestamps without time zone are decoded in the local time zone.
+		if v.Location() == time.Local {
+			return v.UTC(), nil
+		}
+		return v, nil
+	case int8, int16, int32, int64, int, uint8, uint16, uint32, uint64, uint:
+		return json.Number(fmt.Sprint(v)), nil
+	case float32:
+		return floatValue32(v), nil
+	case float64:
+		return floatValue(v), nil
+	case *big.Int:
+		return json.Number(v.String()), nil
+	case big.Int:
+		return json.Number(v.String()), nil
+	case []interface{}:
+		return binaryValues(v)
+	case primitive.A:
+		return binaryValues(v)
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			value, err := binaryValue(item)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = value
+		}
+		return m, nil
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			value, err := binaryValue(item)
+			if err != nil {
+				return nil, err
+			}
+			m[binaryMapKey(key)] = value
+		}
+		return m, nil
+	case primitive.D:
+		m := make(map[string]interface{}, len(v))
+		for _, e := range v {
+			value, err := binaryValue(e.Value)
+			if err != nil {
+				return nil, err
+			}
+			m[e.Key] = value
+		}
+		return m, nil
+	case primitive.M:
+		return binaryValue(map[string]interface{}(v))
+
+	// CBOR
+	case cbor.Tag:
+		// Unknown tags are replaced with the content.
+		return binaryValue(v.Content)
+	case cbor.SimpleValue:
+		return json.Number(strconv.Itoa(int(v))), nil
+
+	// BSON
+	case primitive.ObjectID:
+		return v.Hex(), nil
+	case primitive.DateTime:
+		return v.Time().UTC(), nil
+	case primitive.Timestamp:
+		return fmt.Sprintf("%d:%d", v.T, v.I), nil
+	case primitive.Decimal128:
+		s := v.String()
+		if jsonNumberPattern.MatchString(s) {
+			return json.Number(s), nil
+		}
+		return s, nil
+	case primitive.Binary:
+		if (v.Subtype == bson.TypeBinaryUUID || v.Subtype == bson.TypeBinaryUUIDOld) && len(v.Data) == 16 {
+			return formatUUID(v.Data), nil
+		}
+		return v.Data, nil
+	case primitive.Regex:
+		return "/" + v.Pattern + "/" + v.Options, nil
+	case primitive.JavaScript:
+		return string(v), nil
+	case primitive.Symbol:
+		return string(v), nil
+	case primitive.CodeWithScope:
+		return string(v.Code), nil
+	case primitive.DBPointer:
+		return v.String(), nil
+	case primitive.Null, primitive.Undefined:
+		return nil, nil
+	case primitive.MinKey:
+		return "MinKey", nil
+	case primitive.MaxKey:
+		return "MaxKey", nil
+	default:
+		return nil, fmt.Errorf("unsupported value type %T", v)
+	}
+}
+
+func binaryValues(values []interface{}) ([]interface{}, error) {
+	converted := make([]interface{}, 0, len(values))
+	for _, item := range values {
+		value, err := binaryValue(item)
+		if err != nil {
+			return nil, err
+		}
+		converted = append(converted, value)
+	}
+	return converted, nil
+}
+
+// binaryMapKey returns the string form of non-string map keys.
+func binaryMapKey(key interface{}) string {
+	switch key := key.(type) {
+	case string:
+		return key
+	case []byte:
+		return string(key)
+	default:
+		if value, err := binaryValue(key); err == nil {
+			return fmt.Sprint(value)
+		}
+		return fmt.Sprint(key)
+	}
+}
+
+// floatValue32 is floatValue for float32, which keeps the shortest form of
+// float32 instead of the widened float64. (e.g. 0.1, not 0.10000000149011612)
+func floatValue32(f float32) interface{} {
+	value := floatValue(float64(f))
+	if _, ok := value.(json.Number); ok {
+		s := strconv.FormatFloat(float64(f), 'f', -1, 32)
+		if !strings.Contains(s, ".") {
+			s += ".0"
+		}
+		return json.Number(s)
+	}
+	return value
+}
+
+func formatUUID(b []byte) string {
+	s := hex.EncodeToString(b)
+	return s[0:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:32]
+}
+
+// encodeBinary replaces []byte in the content with strings in the encoding,
+// and time.Time with RFC 3339 strings, so validation and the output see the
+// same values.
+func encodeBinary(data interface{}, encoding string) interface{} {
+	switch v := data.(type) {
+	case []byte:
+		if encoding == hexEncoding {
+			return hex.EncodeToString(v)
+		}
+		return base64.StdEncoding.EncodeToString(v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = encodeBinary(item, encoding)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = encodeBinary(item, encoding)
+		}
+	}
+	return data
+}
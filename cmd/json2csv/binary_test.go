@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func testSequence(t *testing.T, encode func(v map[string]interface{}) ([]byte, error)) []byte {
+	var b bytes.Buffer
+	for i := 1; i <= 3; i++ {
+		data, err := encode(map[string]interface{}{"id": i, "name": "n"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		b.Write(data)
+	}
+	return b.Bytes()
+}
+
+func TestReadBinarySequences(t *testing.T) {
+	var testCases = []struct {
+		name   string
+		read   func(io.Reader) (interface{}, error)
+		encode func(v map[string]interface{}) ([]byte, error)
+	}{
+		{"msgpack", readMessagePack, func(v map[string]interface{}) ([]byte, error) { return msgpack.Marshal(v) }},
+		{"cbor", readCBOR, func(v map[string]interface{}) ([]byte, error) { return cbor.Marshal(v) }},
+		{"bson", readBSON, func(v map[string]interface{}) ([]byte, error) { return bson.Marshal(v) }},
+	}
+	expected := []interface{}{
+		map[string]interface{}{"id": json.Number("1"), "name": "n"},
+		map[string]interface{}{"id": json.Number("2"), "name": "n"},
+		map[string]interface{}{"id": json.Number("3"), "name": "n"},
+	}
+
+	for _, testCase := range testCases {
+		data := testSequence(t, testCase.encode)
+
+		actual, err := testCase.read(bytes.NewReader(data))
+		if err != nil {
+			t.Errorf("%s: %v", testCase.name, err)
+		} else if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("%s: Expected %v, but %v", testCase.name, expected, actual)
+		}
+
+		// a single value is not wrapped in an array
+		single, err := testCase.encode(map[string]interface{}{"id": 1, "name": "n"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		actual, err = testCase.read(bytes.NewReader(single))
+		if err != nil {
+			t.Errorf("%s: %v", testCase.name, err)
+		} else if !reflect.DeepEqual(actual, expected[0]) {
+			t.Errorf("%s: Expected %v, but %v", testCase.name, expected[0], actual)
+		}
+
+		if _, err := testCase.read(bytes.NewReader(data[:len(data)-1])); err == nil {
+			t.Errorf("%s: Expected error for truncated input", testCase.name)
+		}
+		if _, err := testCase.read(bytes.NewReader(nil)); err != io.EOF {
+			t.Errorf("%s: Expected EOF, but %v", testCase.name, err)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// compression formats
+const (
+	noCompression    = ""
+	gzipCompression  = "gzip"
+	bzip2Compression = "bzip2"
+	zstdCompression  = "zstd"
+	xzCompression    = "xz"
+)
+
+var compressionMagics = []struct {
+	format string
+	magic  []byte
+}{
+	{gzipCompression, []byte{0x1f, 0x8b}},
+	{bzip2Compression, []byte("BZh")},
+	{zstdCompression, []byte{0x28, 0xb5, 0x2f, 0xfd}},
+	{xzCompression, []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}},
+}
+
+var compressionExtensions = map[string]string{
+	".gz":   gzipCompression,
+	".gzip": gzipCompression,
+	".bz2":  bzip2Compression,
+	".zst":  zstdCompression,
+	".zstd": zstdCompression,
+	".xz":   xzCompression,
+}
+
+// outputCompressions are formats supported by --compress.
+var outputCompressions = map[string]bool{
+	gzipCompression: true,
+	zstdCompression: true,
+	xzCompression:   true,
+}
+
+// decompressReader returns a reader that decompresses r while reading.
+// The format is detected from the magic bytes, or the extension of the
+// filename if the magic bytes are unknown.
+func decompressReader(r io.Reader, filename string) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	format := noCompression
+	for _, m := range compressionMagics {
+		if head, _ := br.Peek(len(m.magic)); bytes.Equal(head, m.magic) {
+			format = m.format
+			break
+		}
+	}
+	if format == noCompression {
+		format = compressionExtensions[strings.ToLower(filepath.Ext(filename))]
+	}
+
+	switch format {
+	case gzipCompression:
+		return gzip.NewReader(br)
+	case bzip2Compression:
+		return io.NopCloser(bzip2.NewReader(br)), nil
+	case zstdCompression:
+		zr, err := zstd.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		return zr.IOReadCloser(), nil
+	case xzCompression:
+		xr, err := xz.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		return io.NopCloser(xr), nil
+	default:
+		return io.NopCloser(br), nil
+	}
+}
+
+// compressWriter returns a writer that compresses data into w.
+// Close must be called to flush the compressed data.
+func compressWriter(w io.Writer, format string) (io.WriteCloser, error) {
+	switch format {
+	case noCompression:
+		return nopWriteCloser{w}, nil
+	case gzipCompression:
+		return gzip.NewWriter(w), nil
+	case zstdCompression:
+		return zstd.NewWriter(w)
+	case xzCompression:
+		return xz.NewWriter(w)
+	default:
+		return nil, fmt.Errorf("Unsupported compression %q", format)
+	}
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+)
+
+// outputEncoding is a character encoding supported by --output-encoding.
+type outputEncoding struct {
+	encoding encoding.Encoding // nil means UTF-8 as it is
+	bom      []byte
+
+	// name is the name in the XML declaration.
+	name string
+}
+
+var outputEncodingTable = map[string]outputEncoding{
+	"utf-8":        {nil, nil, "UTF-8"},
+	"utf-8-bom":    {nil, []byte("\xef\xbb\xbf"), "UTF-8"},
+	"shift_jis":    {japanese.ShiftJIS, nil, "Shift_JIS"},
+	"euc-jp":       {japanese.EUCJP, nil, "EUC-JP"},
+	"utf-16le":     {unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM), []byte{0xff, 0xfe}, "UTF-16"},
+	"windows-1252": {charmap.Windows1252, nil, "windows-1252"},
+}
+
+// policies for characters which cannot be encoded
+const (
+	errorUnencodable   = "error"
+	replaceUnencodable = "replace"
+	ncrUnencodable     = "ncr"
+)
+
+var unencodableTable = map[string]bool{
+	errorUnencodable:   true,
+	replaceUnencodable: true,
+	ncrUnencodable:     true,
+}
+
+// encodeWriter returns a writer that converts UTF-8 into the encoding.
+// Characters which cannot be encoded are handled by the policy: error,
+// replace (with '?') or ncr (numeric character reference such as "&#9731;").
+// Close must be called to flush the converted data.
+func encodeWriter(w io.Writer, name string, policy string) (io.WriteCloser, error) {
+	e, ok := outputEncodingTable[name]
+	if !ok {
+		return nil, fmt.Errorf("Unsupported encoding %q", name)
+	}
+	if len(e.bom) > 0 {
+		if _, err := w.Write(e.bom); err != nil {
+			return nil, err
+		}
+	}
+	if e.encoding == nil {
+		return nopWriteCloser{w}, nil
+	}
+
+	var t transform.Transformer = e.encoding.NewEncoder()
+	switch policy {
+	case replaceUnencodable:
+		// encoding.ReplaceUnsupported substitutes control characters (e.g.
+		// 0x1a) in some encodings, so replace with '?' before encoding.
+		t = transform.Chain(runes.Map(unencodableReplacer(e.encoding)), t)
+	case ncrUnencodable:
+		t = encoding.HTMLEscapeUnsupported(e.encoding.NewEncoder())
+	}
+	return &encodingWriter{transform.NewWriter(w, t), name}, nil
+}
+
+// unencodableReplacer returns a mapping that replaces characters which
+// cannot be encoded with '?'.
+func unencodableReplacer(e encoding.Encoding) func(rune) rune {
+	encoder := e.NewEncoder()
+	cache := map[rune]bool{}
+	return func(r rune) rune {
+		ok, found := cache[r]
+		if !found {
+			_, err := encoder.String(string(r))
+			ok = err == nil
+			cache[r] = ok
+		}
+		if !ok {
+			return '?'
+		}
+		return r
+	}
+}
+
+// encodingWriter reports unencodable characters with the encoding name.
+type encodingWriter struct {
+	w    io.WriteCloser
+	name string
+}
+
+func (w *encodingWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	return n, w.wrap(err)
+}
+
+func (w *encodingWriter) Close() error {
+	return w.wrap(w.w.Close())
+}
+
+func (w *encodingWriter) wrap(err error) error {
+	if err == nil {
+		return nil
+	}
+	return fmt.Errorf("%s: %s (use --unencodable=replace or ncr)", w.name, err)
+}
+
+// decodeBOM returns a reader that detects the BOM of UTF-8 and UTF-16 and
+// decodes the content into UTF-8 without the BOM. The content without BOM
+// is read as it is.
+func decodeBOM(r io.Reader) io.Reader {
+	return transform.NewReader(r, unicode.BOMOverride(transform.Nop))
+}
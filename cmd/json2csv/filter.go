@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/yukithm/json2csv"
+	"github.com/yukithm/json2csv/expr"
+	"github.com/yukithm/json2csv/jsonpointer"
+)
+
+// whereFilter returns the filter of the --where expression, or nil if it is
+// not specified. The expression is validated in Before.
+func whereFilter(src string) (json2csv.Filter, error) {
+	if src == "" {
+		return nil, nil
+	}
+	e, err := expr.Parse(src)
+	if err != nil {
+		return nil, err
+	}
+	return e.Match, nil
+}
+
+// filterRecords returns the records in data which match the filter. If data
+// is not an array of objects, it is kept or dropped as a whole.
+func filterRecords(data interface{}, filter json2csv.Filter) (interface{}, error) {
+	records, ok := splitRecords(data)
+	if !ok {
+		matched, err := filter(data)
+		if err != nil || !matched {
+			return []interface{}{}, err
+		}
+		return data, nil
+	}
+
+	filtered := []interface{}{}
+	for _, record := range records {
+		matched, err := filter(record)
+		if err != nil {
+			return nil, err
+		}
+		if matched {
+			filtered = append(filtered, record)
+		}
+	}
+	return filtered, nil
+}
+
+// computedColumns parses --add-column values in the form of NAME=EXPR.
+// NAME starting with '/' is a JSON Pointer, otherwise the name of a top-level
+// column.
+func computedColumns(specs []string) ([]json2csv.ComputedColumn, error) {
+	columns := make([]json2csv.ComputedColumn, 0, len(specs))
+	for _, spec := range specs {
+		i := strings.Index(spec, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf("Invalid --add-column value %q (NAME=EXPR)", spec)
+		}
+		name, src := strings.TrimSpace(spec[:i]), spec[i+1:]
+
+		key := name
+		if strings.HasPrefix(name, "/") {
+			if _, err := jsonpointer.New(name); err != nil {
+				return nil, fmt.Errorf("Invalid --add-column value %q: %s", spec, err)
+			}
+		} else {
+			key = "/" + jsonpointer.Token(name).EscapedString()
+		}
+		e, err := expr.Parse(src)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid --add-column value %q: %s", spec, err)
+		}
+		columns = append(columns, json2csv.ComputedColumn{Key: key, Compute: e.Eval})
+	}
+	return columns, nil
+}
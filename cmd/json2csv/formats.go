@@ -0,0 +1,594 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"math/big"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// input formats
+const (
+	jsonFormat  = "json"
+	yamlFormat  = "yaml"
+	tomlFormat  = "toml"
+	json5Format = "json5"
+
+	msgpackFormat = "msgpack"
+	cborFormat    = "cbor"
+	bsonFormat    = "bson"
+)
+
+var inputFormatTable = map[string]bool{
+	jsonFormat:  true,
+	yamlFormat:  true,
+	tomlFormat:  true,
+	json5Format: true,
+
+	msgpackFormat: true,
+	cborFormat:    true,
+	bsonFormat:    true,
+}
+
+var inputFormatExtensions = map[string]string{
+	".json":   jsonFormat,
+	".jsonl":  jsonFormat,
+	".ndjson": jsonFormat,
+	".yaml":   yamlFormat,
+	".yml":    yamlFormat,
+	".toml":   tomlFormat,
+	".json5":  json5Format,
+	".jsonc":  json5Format,
+
+	".msgpack": msgpackFormat,
+	".mpk":     msgpackFormat,
+	".cbor":    cborFormat,
+	".bson":    bsonFormat,
+}
+
+// detectInputFormat returns the format from the extension of the filename,
+// ignoring compression extensions. (e.g. "data.yaml.gz" is YAML)
+func detectInputFormat(filename string) string {
+	ext := strings.ToLower(filepath.Ext(filename))
+	if _, ok := compressionExtensions[ext]; ok {
+		ext = strings.ToLower(filepath.Ext(strings.TrimSuffix(filename, filepath.Ext(filename))))
+	}
+	if format, ok := inputFormatExtensions[ext]; ok {
+		return format
+	}
+	return jsonFormat
+}
+
+// decodeInput decodes the content in the format into the same structure as
+// JSON decoded with UseNumber option.
+// Text formats in UTF-16 with the BOM are also decoded.
+func decodeInput(r io.Reader, format string) (interface{}, error) {
+	switch format {
+	case jsonFormat, yamlFormat, tomlFormat, json5Format:
+		r = decodeBOM(r)
+	}
+
+	switch format {
+	case yamlFormat:
+		return readYAML(r)
+	case tomlFormat:
+		return readTOML(r)
+	case json5Format:
+		return readJSON5(r)
+	case msgpackFormat:
+		return readMessagePack(r)
+	case cborFormat:
+		return readCBOR(r)
+	case bsonFormat:
+		return readBSON(r)
+	default:
+		return readJSON(r)
+	}
+}
+
+// jsonNumberPattern matches numbers valid in JSON.
+var jsonNumberPattern = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][-+]?[0-9]+)?$`)
+
+// readYAML decodes YAML. Multiple documents are returned as an array.
+func readYAML(r io.Reader) (interface{}, error) {
+	decoder := yaml.NewDecoder(r)
+	var docs []interface{}
+	for {
+		var node yaml.Node
+		if err := decoder.Decode(&node); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		doc, err := yamlValue(&node)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+
+	switch len(docs) {
+	case 0:
+		return nil, io.EOF
+	case 1:
+		return docs[0], nil
+	default:
+		return docs, nil
+	}
+}
+
+func yamlValue(node *yaml.Node) (interface{}, error) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			return nil, nil
+		}
+		return yamlValue(node.Content[0])
+	case yaml.AliasNode:
+		return yamlValue(node.Alias)
+	case yaml.SequenceNode:
+		values := make([]interface{}, 0, len(node.Content))
+		for _, item := range node.Content {
+			value, err := yamlValue(item)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		return values, nil
+	case yaml.MappingNode:
+		return yamlMapping(node)
+	case yaml.ScalarNode:
+		return yamlScalar(node)
+	default:
+		return nil, fmt.Errorf("line %d: unsupported YAML node", node.Line)
+	}
+}
+
+func yamlMapping(node *yaml.Node) (map[string]interface{}, error) {
+	m := make(map[string]interface{}, len(node.Content)/2)
+
+	// Merge keys ("<<") have lower priority than keys in the mapping.
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Tag != "!!merge" {
+			continue
+		}
+		sources := []*yaml.Node{node.Content[i+1]}
+		if sources[0].Kind == yaml.SequenceNode {
+			sources = sources[0].Content
+		}
+		for _, source := range sources {
+			merged, err := yamlValue(source)
+			if err != nil {
+				return nil, err
+			}
+			mm, ok := merged.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("line %d: merge value must be a mapping", source.Line)
+			}
+			for k, v := range mm {
+				if _, exists := m[k]; !exists {
+					m[k] = v
+				}
+			}
+		}
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, valueNode := node.Content[i], node.Content[i+1]
+		if key.Tag == "!!merge" {
+			continue
+		}
+		if key.Kind == yaml.AliasNode {
+			key = key.Alias
+		}
+		if key.Kind != yaml.ScalarNode {
+			return nil, fmt.Errorf("line %d: mapping key must be a scalar", key.Line)
+		}
+		value, err := yamlValue(valueNode)
+		if err != nil {
+			return nil, err
+		}
+		m[key.Value] = value
+	}
+	return m, nil
+}
+
+func yamlScalar(node *yaml.Node) (interface{}, error) {
+	switch node.ShortTag() {
+	case "!!null":
+		return nil, nil
+	case "!!bool":
+		var b bool
+		if err := node.Decode(&b); err != nil {
+			return nil, err
+		}
+		return b, nil
+	case "!!int":
+		var i int64
+		if err := node.Decode(&i); err == nil {
+			return json.Number(strconv.FormatInt(i, 10)), nil
+		}
+		var u uint64
+		if err := node.Decode(&u); err == nil {
+			return json.Number(strconv.FormatUint(u, 10)), nil
+		}
+		return node.Value, nil
+	case "!!float":
+		if jsonNumberPattern.MatchString(node.Value) {
+			return json.Number(node.Value), nil
+		}
+		var f float64
+		if err := node.Decode(&f); err != nil {
+			return nil, err
+		}
+		return floatValue(f), nil
+	default:
+		return node.Value, nil
+	}
+}
+
+// floatValue returns json.Number, or the string if the value cannot be
+// represented in JSON. (NaN and Infinity) The number is written without
+// exponent and keeps a decimal point (e.g. 3.0), so it stays a float.
+func floatValue(f float64) interface{} {
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case math.IsInf(f, 1):
+		return "Infinity"
+	case math.IsInf(f, -1):
+		return "-Infinity"
+	}
+	s := strconv.FormatFloat(f, 'f', -1, 64)
+	if !strings.Contains(s, ".") {
+		s += ".0"
+	}
+	return json.Number(s)
+}
+
+// readTOML decodes TOML. Date and time values are converted to strings.
+func readTOML(r io.Reader) (interface{}, error) {
+	var data map[string]interface{}
+	if _, err := toml.NewDecoder(r).Decode(&data); err != nil {
+		return nil, err
+	}
+	return tomlValue(data), nil
+}
+
+func tomlValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			m[key] = tomlValue(item)
+		}
+		return m
+	case []map[string]interface{}:
+		values := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			values = append(values, tomlValue(item))
+		}
+		return values
+	case []interface{}:
+		values := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			values = append(values, tomlValue(item))
+		}
+		return values
+	case int64:
+		return json.Number(strconv.FormatInt(v, 10))
+	case float64:
+		return floatValue(v)
+	case time.Time:
+		// Local date and time values are decoded with the special locations.
+		switch v.Location().String() {
+		case "date-local":
+			return v.Format("2006-01-02")
+		case "time-local":
+			return v.Format("15:04:05.999999999")
+		case "datetime-local":
+			return v.Format("2006-01-02T15:04:05.999999999")
+		default:
+			return v.Format(time.RFC3339Nano)
+		}
+	default:
+		return v
+	}
+}
+
+// readJSON5 decodes JSON5, a superset of JSON which allows comments,
+// trailing commas, unquoted keys, single quoted strings, hexadecimal numbers,
+// Infinity and NaN. JSON with comments (JSONC) is also a subset of JSON5.
+// Infinity and NaN are converted to strings.
+func readJSON5(r io.Reader) (interface{}, error) {
+	src, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	converted, err := json5ToJSON(src)
+	if err != nil {
+		return nil, err
+	}
+	return readJSON(bytes.NewReader(converted))
+}
+
+// json5Scanner converts JSON5 into JSON.
+type json5Scanner struct {
+	src []byte
+	pos int
+	out bytes.Buffer
+}
+
+func json5ToJSON(src []byte) ([]byte, error) {
+	s := &json5Scanner{src: bytes.TrimPrefix(src, []byte("\xef\xbb\xbf"))}
+	for {
+		if err := s.skipSpaces(); err != nil {
+			return nil, err
+		}
+		if s.pos >= len(s.src) {
+			return s.out.Bytes(), nil
+		}
+
+		c := s.src[s.pos]
+		switch {
+		case c == '{' || c == '}' || c == '[' || c == ']' || c == ':':
+			s.out.WriteByte(c)
+			s.pos++
+		case c == ',':
+			s.pos++
+			if err := s.skipSpaces(); err != nil {
+				return nil, err
+			}
+			// Drop trailing commas.
+			if s.pos < len(s.src) && (s.src[s.pos] == '}' || s.src[s.pos] == ']') {
+				continue
+			}
+			s.out.WriteByte(',')
+		case c == '"' || c == '\'':
+			if err := s.scanString(c); err != nil {
+				return nil, err
+			}
+		case c == '-' || c == '+' || c == '.' || (c >= '0' && c <= '9'):
+			if err := s.scanNumber(); err != nil {
+				return nil, err
+			}
+		default:
+			if err := s.scanIdentifier(); err != nil {
+				return nil, err
+			}
+		}
+		s.out.WriteByte(' ')
+	}
+}
+
+func (s *json5Scanner) errorf(format string, args ...interface{}) error {
+	line := bytes.Count(s.src[:s.pos], []byte("\n")) + 1
+	return fmt.Errorf("JSON5 line %d: %s", line, fmt.Sprintf(format, args...))
+}
+
+func (s *json5Scanner) skipSpaces() error {
+	for s.pos < len(s.src) {
+		r, size := utf8.DecodeRune(s.src[s.pos:])
+		switch {
+		case unicode.IsSpace(r) || r == '\ufeff':
+			s.pos += size
+		case bytes.HasPrefix(s.src[s.pos:], []byte("//")):
+			end := bytes.IndexByte(s.src[s.pos:], '\n')
+			if end < 0 {
+				s.pos = len(s.src)
+			} else {
+				s.pos += end + 1
+			}
+		case bytes.HasPrefix(s.src[s.pos:], []byte("/*")):
+			end := bytes.Index(s.src[s.pos+2:], []byte("*/"))
+			if end < 0 {
+				return s.errorf("unterminated comment")
+			}
+			s.pos += end + 4
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+func (s *json5Scanner) scanString(quote byte) error {
+	var b strings.Builder
+	s.pos++
+	for {
+		if s.pos >= len(s.src) {
+			return s.errorf("unterminated string")
+		}
+		c := s.src[s.pos]
+		switch {
+		case c == quote:
+			s.pos++
+			encoded, err := json.Marshal(b.String())
+			if err != nil {
+				return err
+			}
+			s.out.Write(encoded)
+			return nil
+		case c == '\n' || c == '\r':
+			return s.errorf("unescaped line break in string")
+		case c == '\\':
+			if err := s.scanEscape(&b); err != nil {
+				return err
+			}
+		default:
+			r, size := utf8.DecodeRune(s.src[s.pos:])
+			b.WriteRune(r)
+			s.pos += size
+		}
+	}
+}
+
+func (s *json5Scanner) scanEscape(b *strings.Builder) error {
+	s.pos++
+	if s.pos >= len(s.src) {
+		return s.errorf("unterminated string")
+	}
+	c := s.src[s.pos]
+	s.pos++
+	switch c {
+	case 'b':
+		b.WriteByte('\b')
+	case 'f':
+		b.WriteByte('\f')
+	case 'n':
+		b.WriteByte('\n')
+	case 'r':
+		b.WriteByte('\r')
+	case 't':
+		b.WriteByte('\t')
+	case 'v':
+		b.WriteByte('\v')
+	case '0':
+		b.WriteByte(0)
+	case '\r':
+		// line continuation
+		if s.pos < len(s.src) && s.src[s.pos] == '\n' {
+			s.pos++
+		}
+	case '\n':
+		// line continuation
+	case 'x', 'u':
+		n := 2
+		if c == 'u' {
+			n = 4
+		}
+		if s.pos+n > len(s.src) {
+			return s.errorf("invalid escape sequence")
+		}
+		code, err := strconv.ParseUint(string(s.src[s.pos:s.pos+n]), 16, 32)
+		if err != nil {
+			return s.errorf("invalid escape sequence")
+		}
+		s.pos += n
+		r := rune(code)
+		if utf16IsHighSurrogate(r) && bytes.HasPrefix(s.src[s.pos:], []byte(`\u`)) && s.pos+6 <= len(s.src) {
+			if low, err := strconv.ParseUint(string(s.src[s.pos+2:s.pos+6]), 16, 32); err == nil {
+				r = ((r - 0xd800) << 10) + (rune(low) - 0xdc00) + 0x10000
+				s.pos += 6
+			}
+		}
+		b.WriteRune(r)
+	default:
+		s.pos--
+		r, size := utf8.DecodeRune(s.src[s.pos:])
+		b.WriteRune(r)
+		s.pos += size
+	}
+	return nil
+}
+
+func utf16IsHighSurrogate(r rune) bool {
+	return r >= 0xd800 && r < 0xdc00
+}
+
+func (s *json5Scanner) scanNumber() error {
+	start := s.pos
+	for s.pos < len(s.src) {
+		c := s.src[s.pos]
+		if c == ',' || c == '}' || c == ']' || c == ':' || c == '/' {
+			break
+		}
+		if r, _ := utf8.DecodeRune(s.src[s.pos:]); unicode.IsSpace(r) {
+			break
+		}
+		s.pos++
+	}
+	literal := string(s.src[start:s.pos])
+
+	sign := ""
+	body := literal
+	if strings.HasPrefix(body, "+") || strings.HasPrefix(body, "-") {
+		if body[0] == '-' {
+			sign = "-"
+		}
+		body = body[1:]
+	}
+
+	switch {
+	case body == "Infinity" || body == "NaN":
+		if body == "NaN" {
+			sign = ""
+		}
+		encoded, _ := json.Marshal(sign + body)
+		s.out.Write(encoded)
+		return nil
+	case strings.HasPrefix(body, "0x") || strings.HasPrefix(body, "0X"):
+		n, ok := new(big.Int).SetString(body[2:], 16)
+		if !ok {
+			return s.errorf("invalid number %q", literal)
+		}
+		s.out.WriteString(sign + n.String())
+		return nil
+	}
+
+	if strings.HasPrefix(body, ".") {
+		body = "0" + body
+	}
+	if i := strings.IndexAny(body, "eE"); i > 0 && body[i-1] == '.' {
+		body = body[:i-1] + body[i:]
+	} else if strings.HasSuffix(body, ".") {
+		body = body[:len(body)-1]
+	}
+	if !jsonNumberPattern.MatchString(body) {
+		return s.errorf("invalid number %q", literal)
+	}
+	s.out.WriteString(sign + body)
+	return nil
+}
+
+func (s *json5Scanner) scanIdentifier() error {
+	start := s.pos
+	for s.pos < len(s.src) {
+		r, size := utf8.DecodeRune(s.src[s.pos:])
+		if r != '_' && r != '$' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			break
+		}
+		s.pos += size
+	}
+	ident := string(s.src[start:s.pos])
+	if ident == "" {
+		r, _ := utf8.DecodeRune(s.src[s.pos:])
+		return s.errorf("unexpected character %q", r)
+	}
+
+	// An identifier followed by ':' is a key.
+	save := s.pos
+	if err := s.skipSpaces(); err != nil {
+		return err
+	}
+	isKey := s.pos < len(s.src) && s.src[s.pos] == ':'
+	s.pos = save
+
+	switch {
+	case isKey:
+		encoded, _ := json.Marshal(ident)
+		s.out.Write(encoded)
+	case ident == "true" || ident == "false" || ident == "null":
+		s.out.WriteString(ident)
+	case ident == "Infinity" || ident == "NaN":
+		encoded, _ := json.Marshal(ident)
+		s.out.Write(encoded)
+	default:
+		return s.errorf("unexpected identifier %q", ident)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var testReadJSON5Cases = []struct {
+	json5    string
+	expected string
+	err      string
+}{
+	{`{"a": 1}`, `{"a": 1}`, ``},
+	{"\xef\xbb\xbf{\"a\": 1}", `{"a": 1}`, ``},
+
+	// comments
+	{"// head\n{\"a\": 1 // tail\n}", `{"a": 1}`, ``},
+	{`{/* a */ "a": /* b */ 1}`, `{"a": 1}`, ``},
+	{"[1] // no line break", `[1]`, ``},
+	{`{"a": 1 /* open`, ``, `JSON5 line 1: unterminated comment`},
+
+	// trailing commas
+	{`{"a": [1, 2,], "b": 3,}`, `{"a": [1, 2], "b": 3}`, ``},
+	{"[\n  1,\n  // last\n]", `[1]`, ``},
+
+	// unquoted keys
+	{`{a: 1, $b_2: 2, ключ: 3}`, `{"a": 1, "$b_2": 2, "ключ": 3}`, ``},
+	{`{true: 1, null : 2}`, `{"true": 1, "null": 2}`, ``},
+	{`[true, false, null]`, `[true, false, null]`, ``},
+	{`{a: undefined}`, ``, `JSON5 line 1: unexpected identifier "undefined"`},
+	{"{\n  a: @}", ``, `JSON5 line 2: unexpected character '@'`},
+
+	// strings
+	{`['it\'s', "say \"hi\"", 'a"b']`, `["it's", "say \"hi\"", "a\"b"]`, ``},
+	{`['\b\f\n\r\t\v\0', '\x41é\q']`, `["\b\f\n\r\t\u000b\u0000", "Aéq"]`, ``},
+	{`['😀', '\uD83D\uDE00', '\u00e9']`, `["😀", "😀", "é"]`, ``},
+	{"['line \\\ncontinued', 'crlf \\\r\nok']", `["line continued", "crlf ok"]`, ``},
+	{"['broken\nline']", ``, `JSON5 line 1: unescaped line break in string`},
+	{`['\x4']`, ``, `JSON5 line 1: invalid escape sequence`},
+	{`['open`, ``, `JSON5 line 1: unterminated string`},
+
+	// numbers
+	{`[0x1F, -0XFF, +1, .5, 5., 1.e3, -0.25]`, `[31, -255, 1, 0.5, 5, 1e3, -0.25]`, ``},
+	{`[0x10000000000000000]`, `[18446744073709551616]`, ``},
+	{`[Infinity, -Infinity, +Infinity, NaN, -NaN]`, `["Infinity", "-Infinity", "Infinity", "NaN", "NaN"]`, ``},
+	{`{a: Infinity}`, `{"a": "Infinity"}`, ``},
+	{`[0xZZ]`, ``, `JSON5 line 1: invalid number "0xZZ"`},
+	{`[01]`, ``, `JSON5 line 1: invalid number "01"`},
+	{`[1..2]`, ``, `JSON5 line 1: invalid number "1..2"`},
+}
+
+func TestReadJSON5(t *testing.T) {
+	for caseIndex, testCase := range testReadJSON5Cases {
+		actual, err := readJSON5(strings.NewReader(testCase.json5))
+		if testCase.err != "" {
+			if err == nil || err.Error() != testCase.err {
+				t.Errorf("%d: Expected error %v, but %v", caseIndex, testCase.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%d: %v", caseIndex, err)
+			continue
+		}
+		expected, err := readJSON(strings.NewReader(testCase.expected))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("%d: Expected %#v, but %#v", caseIndex, expected, actual)
+		}
+	}
+}
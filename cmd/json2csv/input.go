@@ -0,0 +1,319 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/yukithm/json2csv"
+	"github.com/yukithm/json2csv/jsonpointer"
+	"github.com/yukithm/json2csv/jsonschema"
+
+	"github.com/urfave/cli"
+)
+
+// stdinName is the file name which means STDIN.
+const stdinName = "-"
+
+// input is JSON content read from a file or STDIN.
+type input struct {
+	filename string
+	data     interface{}
+}
+
+// readInputs reads content from each file in the arguments or STDIN in
+// --input-format (or the format detected from the file extension),
+// retrieves the content at --path, and skips invalid records if --validate
+// is specified.
+func readInputs(c *cli.Context) ([]input, error) {
+	filenames, err := inputFiles(c.Args())
+	if err != nil {
+		return nil, err
+	}
+
+	var v *validator
+	if c.String("validate") != "" {
+		v, err = newValidator(c.String("validate"), c.String("reject-file"))
+		if err != nil {
+			return nil, err
+		}
+		defer v.Close()
+	}
+
+	inputs := make([]input, 0, len(filenames))
+	for _, filename := range filenames {
+		format := c.String("input-format")
+		if format == "" {
+			format = detectInputFormat(filename)
+		}
+
+		var data interface{}
+		if filename == stdinName {
+			data, err = readStdin(format)
+		} else {
+			data, err = readFile(filename, format)
+		}
+		if err != nil {
+			return nil, err
+		}
+		data = encodeBinary(data, c.String("binary-encoding"))
+
+		if c.String("path") != "" {
+			data, err = jsonpointer.Get(data, c.String("path"))
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s", filename, err)
+			}
+		}
+
+		if v != nil {
+			data, err = v.validate(filename, data)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		inputs = append(inputs, input{filename, data})
+	}
+	return inputs, nil
+}
+
+// inputFiles expands glob patterns in the arguments.
+// It returns STDIN if there are no arguments.
+func inputFiles(args []string) ([]string, error) {
+	if len(args) == 0 {
+		return []string{stdinName}, nil
+	}
+
+	var filenames []string
+	for _, arg := range args {
+		if arg == stdinName {
+			filenames = append(filenames, arg)
+			continue
+		}
+		matches, err := filepath.Glob(arg)
+		if err != nil {
+			return nil, err
+		}
+		if matches == nil {
+			// Not a pattern, or no matches. Let open report the error.
+			matches = []string{arg}
+		}
+		filenames = append(filenames, matches...)
+	}
+	return filenames, nil
+}
+
+// convertInputs converts each input and merges results.
+// Records which do not match --where are skipped, and --add-column columns
+// are added to each result.
+// If --source-column is specified, the file name is added to each result.
+// Null values are kept if keepNulls is true.
+func convertInputs(c *cli.Context, inputs []input, keepNulls bool) ([]json2csv.KeyValue, error) {
+	filter, err := whereFilter(c.String("where"))
+	if err != nil {
+		return nil, err
+	}
+	computed, err := computedColumns(c.StringSlice("add-column"))
+	if err != nil {
+		return nil, err
+	}
+	converter := &json2csv.Converter{Filter: filter, Computed: computed, KeepNulls: keepNulls}
+
+	sourceKey := sourceColumnKey(c)
+
+	results := []json2csv.KeyValue{}
+	for _, in := range inputs {
+		r, err := converter.Convert(in.data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", in.filename, err)
+		}
+		if sourceKey != "" {
+			for _, result := range r {
+				if err := addSource(result, sourceKey, in.filename); err != nil {
+					return nil, fmt.Errorf("%s: %s", in.filename, err)
+				}
+			}
+		}
+		results = append(results, r...)
+	}
+	return results, nil
+}
+
+// sourceColumnKey returns the key of --source-column, or "" if it is not specified.
+func sourceColumnKey(c *cli.Context) string {
+	if c.String("source-column") == "" {
+		return ""
+	}
+	return "/" + jsonpointer.Token(c.String("source-column")).EscapedString()
+}
+
+// addSource adds the file name to the result. It is an error if the result
+// already has the key.
+func addSource(result json2csv.KeyValue, key, filename string) error {
+	if _, ok := result[key]; ok {
+		return fmt.Errorf("Key %s in the input collides with --source-column", key)
+	}
+	result[key] = filename
+	return nil
+}
+
+// mergeInputs merges records of inputs into an array.
+func mergeInputs(inputs []input) interface{} {
+	if len(inputs) == 1 {
+		return inputs[0].data
+	}
+
+	var records []interface{}
+	for _, in := range inputs {
+		split, _ := splitRecords(in.data)
+		records = append(records, split...)
+	}
+	return records
+}
+
+// splitRecords returns elements and true if data is an array of objects,
+// otherwise data itself and false.
+func splitRecords(data interface{}) ([]interface{}, bool) {
+	records, ok := data.([]interface{})
+	if !ok || len(records) == 0 {
+		return []interface{}{data}, false
+	}
+	for _, record := range records {
+		if _, ok := record.(map[string]interface{}); !ok {
+			return []interface{}{data}, false
+		}
+	}
+	return records, true
+}
+
+func readFile(filename, format string) (interface{}, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r, err := decompressReader(f, filename)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", filename, err)
+	}
+	defer r.Close()
+
+	data, err := decodeInput(r, format)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", filename, err)
+	}
+	return data, nil
+}
+
+func readStdin(format string) (interface{}, error) {
+	r, err := decompressReader(os.Stdin, "")
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return decodeInput(r, format)
+}
+
+// readJSON decodes JSON content. If the content is a sequence of JSON values
+// such as JSON Lines, it returns an array of the values.
+func readJSON(r io.Reader) (interface{}, error) {
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+
+	var data interface{}
+	if err := decoder.Decode(&data); err != nil {
+		return nil, err
+	}
+	if !decoder.More() {
+		return data, nil
+	}
+
+	values := []interface{}{data}
+	for decoder.More() {
+		var value interface{}
+		if err := decoder.Decode(&value); err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// rejectedRecord is a line of --reject-file.
+type rejectedRecord struct {
+	File   string            `json:"file"`
+	Index  int               `json:"index"`
+	Record interface{}       `json:"record"`
+	Errors []validationError `json:"errors"`
+}
+
+type validationError struct {
+	Pointer string `json:"pointer"`
+	Keyword string `json:"keyword"`
+	Message string `json:"message"`
+}
+
+// validator validates records against --validate schema and writes invalid
+// records to --reject-file.
+type validator struct {
+	schema  *jsonschema.Schema
+	encoder *json.Encoder
+	file    *os.File
+}
+
+func newValidator(schemaFile, rejectFile string) (*validator, error) {
+	schema, err := readJSONSchemaFile(schemaFile)
+	if err != nil {
+		return nil, err
+	}
+
+	v := &validator{schema: schema}
+	var w io.Writer = os.Stderr
+	if rejectFile != "" {
+		v.file, err = os.Create(rejectFile)
+		if err != nil {
+			return nil, err
+		}
+		w = v.file
+	}
+	v.encoder = json.NewEncoder(w)
+	v.encoder.SetEscapeHTML(false)
+	return v, nil
+}
+
+func (v *validator) Close() error {
+	if v.file != nil {
+		return v.file.Close()
+	}
+	return nil
+}
+
+// validate returns the content without invalid records.
+func (v *validator) validate(filename string, data interface{}) (interface{}, error) {
+	records, split := splitRecords(data)
+	valid := make([]interface{}, 0, len(records))
+	for i, record := range records {
+		errs := v.schema.Validate(record)
+		if len(errs) == 0 {
+			valid = append(valid, record)
+			continue
+		}
+
+		rejected := rejectedRecord{File: filename, Index: i, Record: record}
+		for _, e := range errs {
+			rejected.Errors = append(rejected.Errors, validationError{e.Pointer.String(), e.Keyword, e.Message})
+		}
+		if err := v.encoder.Encode(rejected); err != nil {
+			return nil, err
+		}
+	}
+
+	if split || len(valid) == 0 {
+		return valid, nil
+	}
+	return data, nil
+}
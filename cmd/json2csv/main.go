@@ -1,16 +1,26 @@
 package main
 
 import (
+	"archive/zip"
+	"database/sql"
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"log"
 	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
 
 	"github.com/yukithm/json2csv"
 	"github.com/yukithm/json2csv/jsonpointer"
+	"github.com/yukithm/json2csv/jsonschema"
 
 	"github.com/urfave/cli"
+	_ "modernc.org/sqlite"
 )
 
 const (
@@ -18,6 +28,9 @@ const (
 	ApplicationName = "json2csv"
 )
 
+// driftExitCode is the exit status when the columns differ from --schema-in.
+const driftExitCode = 3
+
 // injected by build process
 var version = "unknown"
 
@@ -28,6 +41,48 @@ var headerStyleTable = map[string]json2csv.KeyStyle{
 	"dot-bracket": json2csv.DotBracketStyle,
 }
 
+var formatTable = map[string]bool{
+	"csv":     true,
+	"sql":     true,
+	"sqlite":  true,
+	"parquet": true,
+	"xml":     true,
+	"jsonl":   true,
+}
+
+var sqlDialectTable = map[string]json2csv.SQLDialect{
+	"postgres": json2csv.PostgreSQLDialect,
+	"mysql":    json2csv.MySQLDialect,
+	"sqlite":   json2csv.SQLiteDialect,
+}
+
+var driftPolicyTable = map[string]json2csv.DriftPolicy{
+	"error":  json2csv.ErrorOnDrift,
+	"warn":   json2csv.IgnoreDrift,
+	"append": json2csv.AppendDrift,
+}
+
+var parquetCompressionTable = map[string]json2csv.ParquetCompression{
+	"snappy": json2csv.SnappyCompression,
+	"zstd":   json2csv.ZstdCompression,
+	"none":   json2csv.NoCompression,
+}
+
+var xmlStyleTable = map[string]json2csv.XMLStyle{
+	"nested":    json2csv.NestedXMLStyle,
+	"attribute": json2csv.AttributeXMLStyle,
+}
+
+var formulaPrefixTable = map[string]string{
+	"quote": json2csv.DefaultFormulaPrefix,
+	"tab":   "\t",
+}
+
+var dedupeKeepTable = map[string]json2csv.DedupeKeep{
+	"first": json2csv.KeepFirst,
+	"last":  json2csv.KeepLast,
+}
+
 func main() {
 	// Hide timestamp because this is CLI application, so just print message for users.
 	log.SetFlags(0)
@@ -39,6 +94,7 @@ USAGE:
    {{if .UsageText}}{{.UsageText}}{{else}}{{.HelpName}} {{if .Flags}}[OPTIONS]{{end}}{{if .Commands}} command [command options]{{end}} {{if .ArgsUsage}}{{.ArgsUsage}}{{else}}[arguments...]{{end}}{{end}}
 
    If no files are specified, JSON content is read from STDIN.
+   Glob patterns in FILE are expanded. Records in all files are merged.
    {{if .Version}}{{if not .HideVersion}}
 VERSION:
    {{.Version}}
@@ -58,11 +114,16 @@ COPYRIGHT:
    {{end}}
 `
 
+	newApp().RunAndExitOnError()
+}
+
+// newApp returns the application with the options and the commands.
+func newApp() *cli.App {
 	app := cli.NewApp()
 	app.Name = ApplicationName
 	app.Version = version
 	app.Usage = "convert JSON to CSV"
-	app.ArgsUsage = "[FILE]"
+	app.ArgsUsage = "[FILE...]"
 	app.HideHelp = true
 	app.Flags = []cli.Flag{
 		cli.StringFlag{
@@ -74,17 +135,538 @@ COPYRIGHT:
 			Name:  "path",
 			Usage: "target path (JSON Pointer) of the content",
 		},
+		cli.StringFlag{
+			Name:  "input-format",
+			Usage: "input format (json, yaml, toml, json5, msgpack, cbor, bson) (default: detected from the file extension)",
+		},
+		cli.StringFlag{
+			Name:  "binary-encoding",
+			Value: "base64",
+			Usage: "encoding of binary values in msgpack, cbor and bson input (base64, hex)",
+		},
 		cli.BoolFlag{
 			Name:  "transpose",
 			Usage: "transpose rows and columns",
 		},
+		cli.IntFlag{
+			Name:  "transpose-chunk",
+			Usage: "maximum records per block of transposed output, blocks are separated by an empty line (0: no limit)",
+		},
+		cli.BoolFlag{
+			Name:  "transpose-split",
+			Usage: "write each block of --transpose-chunk to a numbered file (e.g. out-1.csv, out-2.csv for --output=out.csv)",
+		},
+		cli.BoolFlag{
+			Name:  "transpose-stream",
+			Usage: "write each block of --transpose-chunk as soon as it is read from JSON input, with the columns of --schema-in",
+		},
+		cli.BoolFlag{
+			Name:  "transpose-types",
+			Usage: "add a column of the inferred types after the header column of transposed output",
+		},
+		cli.StringFlag{
+			Name:  "transpose-descriptions",
+			Usage: "add a column of the descriptions in the JSON file ({\"/pointer\": \"description\"}) after the header column of transposed output",
+		},
+		cli.StringSliceFlag{
+			Name:  "rename",
+			Usage: "rename the header of the column as POINTER=NAME for csv format (e.g. '/user/name=Name')",
+		},
+		cli.StringFlag{
+			Name:  "schema-out",
+			Usage: "write the columns (JSON Pointers) and their order to the file",
+		},
+		cli.StringFlag{
+			Name:  "schema-in",
+			Usage: "use the columns and their order in the file written by --schema-out",
+		},
+		cli.StringFlag{
+			Name:  "json-schema",
+			Usage: "use the columns and cell formats derived from the JSON Schema file",
+		},
+		cli.StringFlag{
+			Name:  "where",
+			Usage: "convert only records matching the expression (e.g. '/status == 200 && /tags contains \"vip\"')",
+		},
+		cli.StringSliceFlag{
+			Name:  "add-column",
+			Usage: "add the column computed from each record as NAME=EXPR (e.g. 'total=/price * /qty')",
+		},
+		cli.BoolFlag{
+			Name:  "dedupe",
+			Usage: "drop rows identical to a previous row",
+		},
+		cli.StringFlag{
+			Name:  "dedupe-key",
+			Usage: "drop rows whose comma separated columns (JSON Pointers) equal a previous row (implies --dedupe)",
+		},
+		cli.StringFlag{
+			Name:  "dedupe-keep",
+			Value: "first",
+			Usage: "row to keep among duplicates for --dedupe (first, last)",
+		},
+		cli.IntFlag{
+			Name:  "dedupe-buffer",
+			Usage: "digests of rows held in memory by --dedupe with --transpose-stream; rows and more digests are spilled to temporary files (0: no limit)",
+		},
+		cli.StringFlag{
+			Name:  "unpivot",
+			Usage: "turn the columns matching the JSON Pointer into key and value rows, '*' matches any token (e.g. /metrics/*)",
+		},
+		cli.StringFlag{
+			Name:  "unpivot-columns",
+			Value: "key,value",
+			Usage: "names of the key and value columns for --unpivot",
+		},
+		cli.StringFlag{
+			Name:  "pivot",
+			Usage: "turn the key and value columns (JSON Pointers) into columns (e.g. /key,/value)",
+		},
+		cli.StringFlag{
+			Name:  "pivot-prefix",
+			Usage: "JSON Pointer under which --pivot puts the columns (e.g. /metrics)",
+		},
+		cli.StringFlag{
+			Name:  "sort-by",
+			Usage: "sort rows by the comma separated columns (JSON Pointers), '-' prefix for descending (e.g. /last_name,-/created_at)",
+		},
+		cli.StringFlag{
+			Name:  "sort-missing",
+			Value: "last",
+			Usage: "position of missing and null values for --sort-by (first, last)",
+		},
+		cli.IntFlag{
+			Name:  "sort-buffer",
+			Usage: "rows sorted in memory at once by --sort-by with --transpose-stream; more are sorted in runs spilled to temporary files (0: no limit)",
+		},
+		cli.StringFlag{
+			Name:  "source-column",
+			Usage: "add the column with the name which holds the input file name of each record",
+		},
+		cli.StringFlag{
+			Name:  "validate",
+			Usage: "skip records that are invalid against the JSON Schema file",
+		},
+		cli.StringFlag{
+			Name:  "reject-file",
+			Usage: "write invalid records and errors as JSON Lines to the file (default: STDERR)",
+		},
+		cli.BoolFlag{
+			Name:  "safe-spreadsheet",
+			Usage: "neutralize cells starting with =, +, -, @, tab or CR which spreadsheets evaluate as formulas",
+		},
+		cli.StringFlag{
+			Name:  "formula-prefix",
+			Value: "quote",
+			Usage: "prefix of neutralized cells for --safe-spreadsheet (quote, tab)",
+		},
+		cli.StringSliceFlag{
+			Name:  "time",
+			Usage: "reformat timestamps in the column as POINTER:KIND[:LAYOUT[:TZ]] (KIND: auto, rfc3339, epoch, epoch_ms, epoch_us, epoch_ns)",
+		},
+		cli.StringFlag{
+			Name:  "bool-literals",
+			Value: "true/false",
+			Usage: "cells of booleans in csv format separated by '/' (e.g. 1/0, TRUE/FALSE, yes/no)",
+		},
+		cli.IntFlag{
+			Name:  "float-precision",
+			Value: -1,
+			Usage: "digits after the decimal point of non-integer numbers in csv format (-1 means as many as necessary)",
+		},
+		cli.StringFlag{
+			Name:  "decimal-separator",
+			Value: ".",
+			Usage: "decimal separator of numbers in csv format",
+		},
+		cli.StringFlag{
+			Name:  "on-drift",
+			Value: "error",
+			Usage: "behavior when new columns are not in --schema-in or --json-schema (error, warn, append)",
+		},
+		cli.BoolFlag{
+			Name:  "normalize",
+			Usage: "split arrays of objects into linked tables written to --output directory or zip archive",
+		},
+		cli.StringFlag{
+			Name:  "id-column",
+			Value: "_id",
+			Usage: "name of the generated id column for --normalize",
+		},
+		cli.StringFlag{
+			Name:  "parent-id-column",
+			Value: "_parent_id",
+			Usage: "name of the generated parent id column for --normalize",
+		},
+		cli.StringFlag{
+			Name:  "format",
+			Value: "csv",
+			Usage: "output format (csv, sql, sqlite, parquet, xml, jsonl)",
+		},
+		cli.StringFlag{
+			Name:  "output, o",
+			Usage: "output file (default: STDOUT)",
+		},
+		cli.StringFlag{
+			Name:  "output-encoding",
+			Value: "utf-8",
+			Usage: "character encoding of the output (utf-8, utf-8-bom, shift_jis, euc-jp, utf-16le, windows-1252)",
+		},
+		cli.StringFlag{
+			Name:  "unencodable",
+			Value: "error",
+			Usage: "behavior for characters which cannot be encoded in --output-encoding (error, replace, ncr)",
+		},
+		cli.StringFlag{
+			Name:  "compress",
+			Usage: "compress the output (gzip, zstd, xz)",
+		},
+		cli.StringFlag{
+			Name:  "table",
+			Usage: "table name for sql, sqlite format and root table name for --normalize",
+		},
+		cli.StringFlag{
+			Name:  "sql-dialect",
+			Value: "postgres",
+			Usage: "SQL dialect for sql format (postgres, mysql, sqlite)",
+		},
+		cli.IntFlag{
+			Name:  "sql-batch-size",
+			Value: json2csv.DefaultSQLBatchSize,
+			Usage: "number of rows per INSERT statement (0 means all rows)",
+		},
+		cli.StringFlag{
+			Name:  "parquet-compression",
+			Value: "snappy",
+			Usage: "compression codec for parquet format (snappy, zstd, none)",
+		},
+		cli.IntFlag{
+			Name:  "parquet-row-group-size",
+			Value: json2csv.DefaultParquetRowGroupSize,
+			Usage: "maximum number of rows per row group for parquet format",
+		},
+		cli.StringFlag{
+			Name:  "xml-style",
+			Value: "nested",
+			Usage: "layout of values for xml format (nested, attribute)",
+		},
+		cli.StringFlag{
+			Name:  "xml-root",
+			Value: json2csv.DefaultXMLRootName,
+			Usage: "root element name for xml format",
+		},
+		cli.StringFlag{
+			Name:  "xml-record",
+			Value: json2csv.DefaultXMLRecordName,
+			Usage: "record element name for xml format",
+		},
+		cli.BoolFlag{
+			Name:  "jsonl-stringify",
+			Usage: "write all values except null as strings for jsonl format",
+		},
 		cli.HelpFlag,
 	}
 
+	app.Commands = []cli.Command{
+		{
+			Name:      "schema",
+			Usage:     "report inferred types and statistics of each column",
+			ArgsUsage: "[FILE...]",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "path",
+					Usage: "target path (JSON Pointer) of the content",
+				},
+				cli.StringFlag{
+					Name:  "input-format",
+					Usage: "input format (json, yaml, toml, json5, msgpack, cbor, bson) (default: detected from the file extension)",
+				},
+				cli.StringFlag{
+					Name:  "binary-encoding",
+					Value: "base64",
+					Usage: "encoding of binary values in msgpack, cbor and bson input (base64, hex)",
+				},
+				cli.StringFlag{
+					Name:  "where",
+					Usage: "convert only records matching the expression (e.g. '/status == 200 && /tags contains \"vip\"')",
+				},
+				cli.StringSliceFlag{
+					Name:  "add-column",
+					Usage: "add the column computed from each record as NAME=EXPR (e.g. 'total=/price * /qty')",
+				},
+				cli.StringFlag{
+					Name:  "format",
+					Value: "table",
+					Usage: "report format (table, json)",
+				},
+			},
+			Before: func(c *cli.Context) error {
+				if c.String("format") != "table" && c.String("format") != "json" {
+					return fmt.Errorf("Invalid --format value %q", c.String("format"))
+				}
+				if c.String("input-format") != "" && !inputFormatTable[c.String("input-format")] {
+					return fmt.Errorf("Invalid --input-format value %q", c.String("input-format"))
+				}
+				if !binaryEncodingTable[c.String("binary-encoding")] {
+					return fmt.Errorf("Invalid --binary-encoding value %q", c.String("binary-encoding"))
+				}
+				if _, err := whereFilter(c.String("where")); err != nil {
+					return fmt.Errorf("Invalid --where value: %s", err)
+				}
+				if _, err := computedColumns(c.StringSlice("add-column")); err != nil {
+					return err
+				}
+				return nil
+			},
+			Action: schemaAction,
+		},
+		{
+			Name:      "aggregate",
+			Usage:     "write a row per group with aggregated values in the format of the global options",
+			ArgsUsage: "[FILE...]",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "path",
+					Usage: "target path (JSON Pointer) of the content",
+				},
+				cli.StringFlag{
+					Name:  "input-format",
+					Usage: "input format (json, yaml, toml, json5, msgpack, cbor, bson) (default: detected from the file extension)",
+				},
+				cli.StringFlag{
+					Name:  "binary-encoding",
+					Value: "base64",
+					Usage: "encoding of binary values in msgpack, cbor and bson input (base64, hex)",
+				},
+				cli.StringFlag{
+					Name:  "where",
+					Usage: "aggregate only records matching the expression",
+				},
+				cli.StringSliceFlag{
+					Name:  "add-column",
+					Usage: "add the column computed from each record as NAME=EXPR before aggregation",
+				},
+				cli.StringFlag{
+					Name:  "source-column",
+					Usage: "add the column with the name which holds the input file name of each record",
+				},
+				cli.StringFlag{
+					Name:  "validate",
+					Usage: "skip records that are invalid against the JSON Schema file",
+				},
+				cli.StringFlag{
+					Name:  "reject-file",
+					Usage: "write invalid records and errors as JSON Lines to the file (default: STDERR)",
+				},
+				cli.StringFlag{
+					Name:  "group-by",
+					Usage: "comma separated columns (JSON Pointers) to group rows by (default: all rows in a group)",
+				},
+				cli.StringFlag{
+					Name:  "agg",
+					Value: "count()",
+					Usage: "comma separated aggregate functions (count(), count(/ptr), sum(/ptr), avg(/ptr), min(/ptr), max(/ptr))",
+				},
+			},
+			Before: func(c *cli.Context) error {
+				if c.String("input-format") != "" && !inputFormatTable[c.String("input-format")] {
+					return fmt.Errorf("Invalid --input-format value %q", c.String("input-format"))
+				}
+				if !binaryEncodingTable[c.String("binary-encoding")] {
+					return fmt.Errorf("Invalid --binary-encoding value %q", c.String("binary-encoding"))
+				}
+				if _, err := whereFilter(c.String("where")); err != nil {
+					return fmt.Errorf("Invalid --where value: %s", err)
+				}
+				if _, err := computedColumns(c.StringSlice("add-column")); err != nil {
+					return err
+				}
+				keys, err := parsePointerList("group-by", c.String("group-by"))
+				if err != nil {
+					return err
+				}
+				aggs, err := parseAggregations(c.String("agg"))
+				if err != nil {
+					return err
+				}
+				if err := json2csv.CheckAggregationKeys(keys, aggs); err != nil {
+					return err
+				}
+				if c.Parent().Bool("normalize") {
+					return fmt.Errorf("--normalize cannot be used with aggregate")
+				}
+				// The command reads the input with its own options.
+				for _, name := range aggregateInputFlags {
+					if c.Parent().IsSet(name) {
+						return fmt.Errorf("--%s must be given after aggregate", name)
+					}
+				}
+				return nil
+			},
+			Action: aggregateAction,
+		},
+	}
+
 	app.Before = func(c *cli.Context) error {
 		if _, ok := headerStyleTable[c.String("header-style")]; !ok {
 			return fmt.Errorf("Invalid --header-style value %q", c.String("header-style"))
 		}
+		if !formatTable[c.String("format")] {
+			return fmt.Errorf("Invalid --format value %q", c.String("format"))
+		}
+		if c.String("input-format") != "" && !inputFormatTable[c.String("input-format")] {
+			return fmt.Errorf("Invalid --input-format value %q", c.String("input-format"))
+		}
+		if !binaryEncodingTable[c.String("binary-encoding")] {
+			return fmt.Errorf("Invalid --binary-encoding value %q", c.String("binary-encoding"))
+		}
+		if _, err := whereFilter(c.String("where")); err != nil {
+			return fmt.Errorf("Invalid --where value: %s", err)
+		}
+		if _, err := computedColumns(c.StringSlice("add-column")); err != nil {
+			return err
+		}
+		if _, err := parsePointerList("dedupe-key", c.String("dedupe-key")); err != nil {
+			return err
+		}
+		if _, ok := dedupeKeepTable[c.String("dedupe-keep")]; !ok {
+			return fmt.Errorf("Invalid --dedupe-keep value %q", c.String("dedupe-keep"))
+		}
+		if c.Int("dedupe-buffer") < 0 {
+			return fmt.Errorf("Invalid --dedupe-buffer value %d", c.Int("dedupe-buffer"))
+		}
+		if c.Int("dedupe-buffer") > 0 && (!c.Bool("dedupe") && c.String("dedupe-key") == "" || !c.Bool("transpose-stream")) {
+			return fmt.Errorf("--dedupe-buffer requires --dedupe and --transpose-stream")
+		}
+		if c.String("unpivot") != "" {
+			if _, err := jsonpointer.New(c.String("unpivot")); err != nil {
+				return fmt.Errorf("Invalid --unpivot value %q", c.String("unpivot"))
+			}
+			if c.String("pivot") != "" {
+				return fmt.Errorf("--unpivot and --pivot cannot be used together")
+			}
+		}
+		if _, _, err := unpivotColumns(c.String("unpivot-columns")); err != nil {
+			return err
+		}
+		if c.String("pivot") != "" {
+			if _, _, err := pivotColumns(c.String("pivot")); err != nil {
+				return err
+			}
+		}
+		if _, err := jsonpointer.New(c.String("pivot-prefix")); err != nil {
+			return fmt.Errorf("Invalid --pivot-prefix value %q", c.String("pivot-prefix"))
+		}
+		if _, err := parseSortKeys(c.String("sort-by")); err != nil {
+			return err
+		}
+		if _, ok := missingOrderTable[c.String("sort-missing")]; !ok {
+			return fmt.Errorf("Invalid --sort-missing value %q", c.String("sort-missing"))
+		}
+		if c.Int("sort-buffer") < 0 {
+			return fmt.Errorf("Invalid --sort-buffer value %d", c.Int("sort-buffer"))
+		}
+		if c.Int("sort-buffer") > 0 && (c.String("sort-by") == "" || !c.Bool("transpose-stream")) {
+			return fmt.Errorf("--sort-buffer requires --sort-by and --transpose-stream")
+		}
+		if _, ok := sqlDialectTable[c.String("sql-dialect")]; !ok {
+			return fmt.Errorf("Invalid --sql-dialect value %q", c.String("sql-dialect"))
+		}
+		if _, ok := parquetCompressionTable[c.String("parquet-compression")]; !ok {
+			return fmt.Errorf("Invalid --parquet-compression value %q", c.String("parquet-compression"))
+		}
+		if _, ok := outputEncodingTable[c.String("output-encoding")]; !ok {
+			return fmt.Errorf("Invalid --output-encoding value %q", c.String("output-encoding"))
+		}
+		if !unencodableTable[c.String("unencodable")] {
+			return fmt.Errorf("Invalid --unencodable value %q", c.String("unencodable"))
+		}
+		if c.String("output-encoding") != "utf-8" && (c.String("format") == "sqlite" || c.String("format") == "parquet") {
+			return fmt.Errorf("--output-encoding cannot be used with --format=sqlite and --format=parquet")
+		}
+		if _, ok := formulaPrefixTable[c.String("formula-prefix")]; !ok {
+			return fmt.Errorf("Invalid --formula-prefix value %q", c.String("formula-prefix"))
+		}
+		if _, err := parseTimeRules(c.StringSlice("time")); err != nil {
+			return err
+		}
+		if len(strings.Split(c.String("bool-literals"), "/")) != 2 {
+			return fmt.Errorf("Invalid --bool-literals value %q", c.String("bool-literals"))
+		}
+		if _, ok := xmlStyleTable[c.String("xml-style")]; !ok {
+			return fmt.Errorf("Invalid --xml-style value %q", c.String("xml-style"))
+		}
+		if c.String("format") == "sql" && c.String("table") == "" {
+			return fmt.Errorf("--table is required for --format=sql")
+		}
+		if c.String("compress") != "" {
+			if !outputCompressions[c.String("compress")] {
+				return fmt.Errorf("Invalid --compress value %q", c.String("compress"))
+			}
+			if c.String("format") == "sqlite" || c.Bool("normalize") {
+				return fmt.Errorf("--compress cannot be used with --format=sqlite and --normalize")
+			}
+		}
+		if c.String("format") == "sqlite" && c.String("output") == "" {
+			return fmt.Errorf("--output is required for --format=sqlite")
+		}
+		if _, ok := driftPolicyTable[c.String("on-drift")]; !ok {
+			return fmt.Errorf("Invalid --on-drift value %q", c.String("on-drift"))
+		}
+		if c.String("schema-in") != "" || c.String("schema-out") != "" || c.String("json-schema") != "" {
+			if c.String("format") != "csv" || c.Bool("normalize") {
+				return fmt.Errorf("--schema-in, --schema-out and --json-schema support only --format=csv")
+			}
+		}
+		if _, err := parseRenames(c.StringSlice("rename")); err != nil {
+			return err
+		}
+		if c.Int("transpose-chunk") < 0 {
+			return fmt.Errorf("Invalid --transpose-chunk value %d", c.Int("transpose-chunk"))
+		}
+		if c.Int("transpose-chunk") > 0 || c.Bool("transpose-split") || c.Bool("transpose-stream") || c.Bool("transpose-types") || c.String("transpose-descriptions") != "" {
+			if !c.Bool("transpose") || c.String("format") != "csv" || c.Bool("normalize") {
+				return fmt.Errorf("--transpose-chunk, --transpose-split, --transpose-stream, --transpose-types and --transpose-descriptions require --transpose and --format=csv")
+			}
+		}
+		if c.Bool("transpose-stream") {
+			if c.Int("transpose-chunk") == 0 || c.String("schema-in") == "" {
+				return fmt.Errorf("--transpose-stream requires --transpose-chunk and --schema-in")
+			}
+			if c.String("input-format") != "" && c.String("input-format") != jsonFormat {
+				return fmt.Errorf("--transpose-stream supports only JSON input")
+			}
+			// they need all records before writing
+			if c.String("path") != "" || c.String("validate") != "" || c.String("unpivot") != "" || c.String("pivot") != "" || c.Bool("transpose-types") || c.String("on-drift") == "append" {
+				return fmt.Errorf("--path, --validate, --unpivot, --pivot, --transpose-types and --on-drift=append cannot be used with --transpose-stream")
+			}
+		}
+		if c.Bool("transpose-split") {
+			// "-" would be taken as STDOUT, but the blocks are written to files
+			if c.Int("transpose-chunk") == 0 || c.String("output") == "" || c.String("output") == "-" {
+				return fmt.Errorf("--transpose-split requires --transpose-chunk and --output of a file")
+			}
+			if c.String("compress") != "" {
+				return fmt.Errorf("--transpose-split cannot be used with --compress")
+			}
+		}
+		if c.String("schema-in") != "" && c.String("json-schema") != "" {
+			return fmt.Errorf("--schema-in and --json-schema cannot be used together")
+		}
+		if c.Bool("normalize") {
+			if c.String("source-column") != "" || len(c.StringSlice("add-column")) > 0 || c.String("sort-by") != "" || c.Bool("dedupe") || c.String("dedupe-key") != "" || c.String("unpivot") != "" || c.String("pivot") != "" {
+				return fmt.Errorf("--source-column, --add-column, --sort-by, --dedupe, --unpivot and --pivot cannot be used with --normalize")
+			}
+			if c.String("output") == "" {
+				return fmt.Errorf("--output is required for --normalize")
+			}
+			if c.String("id-column") == "" || c.String("parent-id-column") == "" || c.String("id-column") == c.String("parent-id-column") {
+				return fmt.Errorf("--id-column and --parent-id-column must be different names")
+			}
+			if c.String("format") != "csv" {
+				return fmt.Errorf("--normalize supports only --format=csv")
+			}
+		}
 		return nil
 	}
 
@@ -95,30 +677,37 @@ COPYRIGHT:
 		}
 		mainAction(c)
 	}
-
-	app.RunAndExitOnError()
+	return app
 }
 
 func mainAction(c *cli.Context) {
-	var data interface{}
-	var err error
-	if c.NArg() > 0 && c.Args()[0] != "-" {
-		data, err = readJSONFile(c.Args()[0])
-	} else {
-		data, err = readJSON(os.Stdin)
+	if c.Bool("transpose-stream") {
+		exitOnError(writeTransposedStream(c))
+		return
 	}
+
+	inputs, err := readInputs(c)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	if c.String("path") != "" {
-		data, err = jsonpointer.Get(data, c.String("path"))
-		if err != nil {
+	if c.Bool("normalize") {
+		data := mergeInputs(inputs)
+		if filter, _ := whereFilter(c.String("where")); filter != nil {
+			data, err = filterRecords(data, filter)
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+		if err := writeNormalized(c, data); err != nil {
 			log.Fatal(err)
 		}
+		return
 	}
 
-	results, err := json2csv.JSON2CSV(data)
+	// null columns are written in SQL
+	keepNulls := c.String("format") == "sql" || c.String("format") == "sqlite"
+	results, err := convertInputs(c, inputs, keepNulls)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -126,41 +715,519 @@ func mainAction(c *cli.Context) {
 		return
 	}
 
-	headerStyle := headerStyleTable[c.String("header-style")]
-	err = printCSV(os.Stdout, results, headerStyle, c.Bool("transpose"))
+	results = dedupeResults(c, results)
+	results, err = reshapeResults(c, results)
 	if err != nil {
 		log.Fatal(err)
 	}
+	outputResults(c, results, nil)
 }
 
-func readJSONFile(filename string) (interface{}, error) {
+// dedupeResults removes duplicates by --dedupe and --dedupe-key.
+func dedupeResults(c *cli.Context, results []json2csv.KeyValue) []json2csv.KeyValue {
+	if !c.Bool("dedupe") && c.String("dedupe-key") == "" {
+		return results
+	}
+	// validated in app.Before
+	keys, _ := parsePointerList("dedupe-key", c.String("dedupe-key"))
+	return json2csv.DedupeResults(results, keys, dedupeKeepTable[c.String("dedupe-keep")])
+}
+
+// outputResults sorts results by --sort-by and writes them. It exits with
+// driftExitCode if there are new columns.
+func outputResults(c *cli.Context, results []json2csv.KeyValue, columns []string) {
+	sortResults(c, results)
+	exitOnError(writeResults(c, results, columns))
+}
+
+// exitOnError exits with driftExitCode if err is *json2csv.DriftError, or
+// with 1 if it is another error.
+func exitOnError(err error) {
+	if err == nil {
+		return
+	}
+	if _, ok := err.(*json2csv.DriftError); ok {
+		log.Print(err)
+		os.Exit(driftExitCode)
+	}
+	log.Fatal(err)
+}
+
+// sortResults sorts results by --sort-by.
+func sortResults(c *cli.Context, results []json2csv.KeyValue) {
+	// validated in app.Before
+	keys, _ := parseSortKeys(c.String("sort-by"))
+	json2csv.SortResults(results, keys, missingOrderTable[c.String("sort-missing")])
+}
+
+// writeResults writes results in --format. columns fixes the columns of csv
+// format unless --schema-in or --json-schema is specified.
+func writeResults(c *cli.Context, results []json2csv.KeyValue, columns []string) error {
+	headerStyle := headerStyleTable[c.String("header-style")]
+	if c.String("format") == "sqlite" {
+		return writeSQLite(c.String("output"), results, tableName(c), headerStyle)
+	}
+
+	if c.Bool("transpose-split") {
+		return writeTransposedFiles(c, results, headerStyle, columns)
+	}
+
+	// resolve the columns before the output is truncated, so that nothing
+	// is written if there are new columns and --on-drift=error
+	var csvConfig *json2csv.CSVWriter
+	var drift []string
+	if c.String("format") == "csv" {
+		var err error
+		csvConfig, _, drift, err = schemaCSVWriter(c, ioutil.Discard, results, headerStyle, columns)
+		if err != nil {
+			return err
+		}
+	}
+
+	w, err := openOutput(c)
+	if err != nil {
+		return err
+	}
+
+	switch c.String("format") {
+	case "sql":
+		dialect := sqlDialectTable[c.String("sql-dialect")]
+		err = printSQL(w, results, c.String("table"), dialect, headerStyle, c.Int("sql-batch-size"))
+	case "parquet":
+		compression := parquetCompressionTable[c.String("parquet-compression")]
+		err = printParquet(w, results, headerStyle, compression, c.Int("parquet-row-group-size"))
+	case "jsonl":
+		err = printJSONL(w, results, headerStyle, c.Bool("jsonl-stringify"), newValueFormatter(c))
+	case "xml":
+		encoding := outputEncodingTable[c.String("output-encoding")].name
+		err = printXML(w, results, xmlStyleTable[c.String("xml-style")], c.String("xml-root"), c.String("xml-record"), encoding)
+	default:
+		err = printCSVWithSchema(csvWriterTo(csvConfig, w), results, drift)
+	}
+	if closeErr := w.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// outputWriter writes to --output (or STDOUT) with --compress and
+// --output-encoding. Close closes them in order.
+type outputWriter struct {
+	io.Writer
+	closers []io.Closer
+}
+
+func (w *outputWriter) Close() error {
+	var err error
+	for _, closer := range w.closers {
+		if closeErr := closer.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// openOutput creates --output, or returns STDOUT, wrapped by --compress and
+// --output-encoding (except for parquet format).
+func openOutput(c *cli.Context) (*outputWriter, error) {
+	var out io.Writer = os.Stdout
+	var closers []io.Closer
+	if c.String("output") != "" {
+		f, err := os.Create(c.String("output"))
+		if err != nil {
+			return nil, err
+		}
+		out = f
+		closers = append(closers, f)
+	}
+
+	cw, err := compressWriter(out, c.String("compress"))
+	if err != nil {
+		closeAll(closers)
+		return nil, err
+	}
+	closers = append([]io.Closer{cw}, closers...)
+	if c.String("format") == "parquet" {
+		return &outputWriter{cw, closers}, nil
+	}
+
+	w, err := encodeWriter(cw, c.String("output-encoding"), c.String("unencodable"))
+	if err != nil {
+		closeAll(closers)
+		return nil, err
+	}
+	return &outputWriter{w, append([]io.Closer{w}, closers...)}, nil
+}
+
+func closeAll(closers []io.Closer) {
+	for _, closer := range closers {
+		closer.Close()
+	}
+}
+
+// columnsFile is the file format of --schema-out and --schema-in.
+type columnsFile struct {
+	Columns []string `json:"columns"`
+}
+
+// printCSVWithSchema writes CSV with CSVWriter returned by schemaCSVWriter.
+// It returns *json2csv.DriftError if there are new columns even though they
+// are handled by --on-drift.
+func printCSVWithSchema(csv *json2csv.CSVWriter, results []json2csv.KeyValue, drift []string) error {
+	if err := csv.WriteCSV(results); err != nil {
+		return err
+	}
+	if len(drift) > 0 {
+		return &json2csv.DriftError{Keys: drift}
+	}
+	return nil
+}
+
+// schemaCSVWriter returns CSVWriter configured by --schema-in, --json-schema
+// and the transposed output options, with the columns to be written and the
+// new columns. It writes the columns to --schema-out.
+func schemaCSVWriter(c *cli.Context, w io.Writer, results []json2csv.KeyValue, headerStyle json2csv.KeyStyle, columns []string) (*json2csv.CSVWriter, []string, []string, error) {
+	csv := newCSVWriter(c, w, headerStyle)
+	if columns != nil {
+		csv.Columns = columns
+		csv.Drift = json2csv.AppendDrift
+	}
+	if c.String("schema-in") != "" {
+		columns, err := readColumnsFile(c.String("schema-in"))
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		csv.Columns = columns
+		csv.Drift = driftPolicyTable[c.String("on-drift")]
+	}
+	if c.String("json-schema") != "" {
+		schema, err := readJSONSchemaFile(c.String("json-schema"))
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		columns, formatters, err := json2csv.JSONSchemaLayout(schema, results, csv.ValueFormatter)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		csv.Columns = columns
+		for key, format := range formatters {
+			// --time takes precedence
+			if _, ok := csv.Formatters[key]; !ok {
+				csv.Formatters[key] = format
+			}
+		}
+		csv.Drift = driftPolicyTable[c.String("on-drift")]
+	}
+
+	columns, drift, err := csv.ResolveColumns(results)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if c.String("schema-out") != "" {
+		if err := writeColumnsFile(c.String("schema-out"), columns); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	csv.Annotations, err = transposeAnnotations(c, results, columns)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return csv, columns, drift, nil
+}
+
+func readColumnsFile(filename string) ([]string, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var file columnsFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("%s: %s", filename, err)
+	}
+	for _, column := range file.Columns {
+		if _, err := jsonpointer.New(column); err != nil {
+			return nil, fmt.Errorf("%s: %s", filename, err)
+		}
+	}
+	if file.Columns == nil {
+		file.Columns = []string{}
+	}
+	return file.Columns, nil
+}
+
+func readJSONSchemaFile(filename string) (*jsonschema.Schema, error) {
 	f, err := os.Open(filename)
 	if err != nil {
 		return nil, err
 	}
 	defer f.Close()
 
-	return readJSON(f)
+	schema, err := jsonschema.Read(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", filename, err)
+	}
+	return schema, nil
 }
 
-func readJSON(r io.Reader) (interface{}, error) {
-	decoder := json.NewDecoder(r)
-	decoder.UseNumber()
+func writeColumnsFile(filename string, columns []string) error {
+	data, err := json.MarshalIndent(columnsFile{columns}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, append(data, '\n'), 0644)
+}
 
-	var data interface{}
-	if err := decoder.Decode(&data); err != nil {
-		return nil, err
+// writeNormalized writes each normalized table as "<table>.csv" into the
+// output directory, or into the zip archive if the output ends with ".zip".
+func writeNormalized(c *cli.Context, data interface{}) error {
+	normalizer := &json2csv.Normalizer{
+		IDKey:       "/" + jsonpointer.Token(c.String("id-column")).EscapedString(),
+		ParentIDKey: "/" + jsonpointer.Token(c.String("parent-id-column")).EscapedString(),
+	}
+	tables, err := normalizer.Normalize(data, tableName(c))
+	if err != nil {
+		return err
+	}
+
+	headerStyle := headerStyleTable[c.String("header-style")]
+	output := c.String("output")
+	if strings.EqualFold(filepath.Ext(output), ".zip") {
+		f, err := os.Create(output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		zw := zip.NewWriter(f)
+		for _, table := range tables {
+			if len(table.Records) == 0 {
+				continue
+			}
+			w, err := zw.CreateHeader(&zip.FileHeader{
+				Name:     tableFileName(table.Name),
+				Method:   zip.Deflate,
+				Modified: time.Now(),
+			})
+			if err != nil {
+				return err
+			}
+			if err := printCSV(c, w, table.Records, headerStyle); err != nil {
+				return err
+			}
+		}
+		if err := zw.Close(); err != nil {
+			return err
+		}
+		return f.Close()
 	}
 
-	return data, nil
+	if err := os.MkdirAll(output, 0755); err != nil {
+		return err
+	}
+	for _, table := range tables {
+		if len(table.Records) == 0 {
+			continue
+		}
+		f, err := os.Create(filepath.Join(output, tableFileName(table.Name)))
+		if err != nil {
+			return err
+		}
+		err = printCSV(c, f, table.Records, headerStyle)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-func printCSV(w io.Writer, results []json2csv.KeyValue, headerStyle json2csv.KeyStyle, transpose bool) error {
+// tableName returns --table value or the base name of --output without extension.
+func tableName(c *cli.Context) string {
+	if c.String("table") != "" {
+		return c.String("table")
+	}
+	base := filepath.Base(c.String("output"))
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+// parsePointerList parses the flag value of comma separated JSON Pointers.
+func parsePointerList(flag, spec string) ([]string, error) {
+	if spec == "" {
+		return nil, nil
+	}
+
+	var keys []string
+	for _, key := range strings.Split(spec, ",") {
+		key = strings.TrimSpace(key)
+		if _, err := jsonpointer.New(key); err != nil || key == "" {
+			return nil, fmt.Errorf("Invalid --%s value %q", flag, key)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+var unsafeFileNameChars = strings.NewReplacer("/", "_", "\\", "_", ":", "_")
+
+func tableFileName(name string) string {
+	return unsafeFileNameChars.Replace(name) + ".csv"
+}
+
+func schemaAction(c *cli.Context) {
+	inputs, err := readInputs(c)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	results, err := convertInputs(c, inputs, true)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	schema, err := json2csv.InferSchema(results)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if c.String("format") == "json" {
+		err = printSchemaJSON(os.Stdout, schema)
+	} else {
+		err = printSchemaTable(os.Stdout, schema)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func printSchemaJSON(w io.Writer, schema *json2csv.Schema) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(schema)
+}
+
+func printSchemaTable(w io.Writer, schema *json2csv.Schema) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "POINTER\tTYPE\tJSON TYPES\tCOUNT\tNULL\tMISSING\tMIN\tMAX\tLENGTH\tSAMPLES")
+	for _, column := range schema.Columns {
+		length := ""
+		if column.MinLength != nil {
+			length = fmt.Sprintf("%d-%d", *column.MinLength, *column.MaxLength)
+		}
+		samples := make([]string, 0, len(column.Samples))
+		for _, sample := range column.Samples {
+			samples = append(samples, fmt.Sprintf("%q", fmt.Sprint(sample)))
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%d\t%d\t%s\t%s\t%s\t%s\n",
+			column.Pointer,
+			column.Type,
+			strings.Join(column.Types, ","),
+			column.Count,
+			column.NullCount,
+			column.MissingCount,
+			column.Min,
+			column.Max,
+			length,
+			strings.Join(samples, ", "))
+	}
+	return tw.Flush()
+}
+
+// newValueFormatter returns StandardValueFormatter configured by
+// --bool-literals, --float-precision and --decimal-separator.
+func newValueFormatter(c *cli.Context) *json2csv.StandardValueFormatter {
+	literals := strings.Split(c.String("bool-literals"), "/")
+	formatter := json2csv.NewStandardValueFormatter()
+	formatter.TrueLiteral = literals[0]
+	formatter.FalseLiteral = literals[1]
+	formatter.FloatPrecision = c.Int("float-precision")
+	formatter.DecimalSeparator = c.String("decimal-separator")
+	return formatter
+}
+
+// newCSVWriter returns CSVWriter configured by --transpose, --transpose-chunk,
+// --rename, --time, --safe-spreadsheet and the cell format options.
+func newCSVWriter(c *cli.Context, w io.Writer, headerStyle json2csv.KeyStyle) *json2csv.CSVWriter {
+	// validated in app.Before
+	rules, _ := parseTimeRules(c.StringSlice("time"))
+
 	csv := json2csv.NewCSVWriter(w)
 	csv.HeaderStyle = headerStyle
-	csv.Transpose = transpose
+	csv.Transpose = c.Bool("transpose")
+	csv.ChunkSize = c.Int("transpose-chunk")
+	csv.HeaderNames, _ = parseRenames(c.StringSlice("rename"))
+	csv.ValueFormatter = newValueFormatter(c)
+	csv.SafeSpreadsheet = c.Bool("safe-spreadsheet")
+	csv.FormulaPrefix = formulaPrefixTable[c.String("formula-prefix")]
+	csv.Formatters = json2csv.TimeFormatters(rules, func(err error) {
+		log.Printf("Warning: %s", err)
+	})
+	return csv
+}
+
+// csvWriterTo returns a copy of CSVWriter which writes to w.
+func csvWriterTo(config *json2csv.CSVWriter, w io.Writer) *json2csv.CSVWriter {
+	cw := *config
+	cw.Writer = csv.NewWriter(w)
+	return &cw
+}
+
+// printCSV writes CSV in --output-encoding.
+func printCSV(c *cli.Context, w io.Writer, results []json2csv.KeyValue, headerStyle json2csv.KeyStyle) error {
+	ew, err := encodeWriter(w, c.String("output-encoding"), c.String("unencodable"))
+	if err != nil {
+		return err
+	}
+	csv := newCSVWriter(c, ew, headerStyle)
 	if err := csv.WriteCSV(results); err != nil {
 		return err
 	}
-	return nil
+	return ew.Close()
+}
+
+func printSQL(w io.Writer, results []json2csv.KeyValue, table string, dialect json2csv.SQLDialect, headerStyle json2csv.KeyStyle, batchSize int) error {
+	sql := json2csv.NewSQLWriter(w, table)
+	sql.Dialect = dialect
+	sql.HeaderStyle = headerStyle
+	sql.BatchSize = batchSize
+	return sql.WriteSQL(results)
+}
+
+func printParquet(w io.Writer, results []json2csv.KeyValue, headerStyle json2csv.KeyStyle, compression json2csv.ParquetCompression, rowGroupSize int) error {
+	parquet := json2csv.NewParquetWriter(w)
+	parquet.HeaderStyle = headerStyle
+	parquet.Compression = compression
+	parquet.RowGroupSize = rowGroupSize
+	return parquet.WriteParquet(results)
+}
+
+func printJSONL(w io.Writer, results []json2csv.KeyValue, headerStyle json2csv.KeyStyle, stringify bool, formatter json2csv.ValueFormatter) error {
+	jsonl := json2csv.NewJSONLWriter(w)
+	jsonl.HeaderStyle = headerStyle
+	jsonl.Stringify = stringify
+	jsonl.ValueFormatter = formatter
+	return jsonl.WriteJSONL(results)
+}
+
+func printXML(w io.Writer, results []json2csv.KeyValue, style json2csv.XMLStyle, root, record, encoding string) error {
+	xml := json2csv.NewXMLWriter(w)
+	xml.Encoding = encoding
+	xml.Style = style
+	xml.RootName = root
+	xml.RecordName = record
+	return xml.WriteXML(results)
+}
+
+func writeSQLite(filename string, results []json2csv.KeyValue, table string, headerStyle json2csv.KeyStyle) error {
+	db, err := sql.Open("sqlite", filename)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	sqlite := json2csv.NewSQLiteWriter(db, table)
+	sqlite.HeaderStyle = headerStyle
+	return sqlite.WriteSQLite(results)
 }
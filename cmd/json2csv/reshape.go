@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/yukithm/json2csv"
+	"github.com/yukithm/json2csv/jsonpointer"
+
+	"github.com/urfave/cli"
+)
+
+// unpivotColumns parses --unpivot-columns value, names of the key and value
+// columns separated by a comma.
+func unpivotColumns(spec string) (string, string, error) {
+	names := strings.Split(spec, ",")
+	if len(names) != 2 || strings.TrimSpace(names[0]) == "" || strings.TrimSpace(names[1]) == "" {
+		return "", "", fmt.Errorf("Invalid --unpivot-columns value %q", spec)
+	}
+	keys := make([]string, 2)
+	for i, name := range names {
+		keys[i] = "/" + jsonpointer.Token(strings.TrimSpace(name)).EscapedString()
+	}
+	return keys[0], keys[1], nil
+}
+
+// pivotColumns parses --pivot value, JSON Pointers of the key and value
+// columns separated by a comma.
+func pivotColumns(spec string) (string, string, error) {
+	keys, err := parsePointerList("pivot", spec)
+	if err != nil {
+		return "", "", err
+	}
+	if len(keys) != 2 {
+		return "", "", fmt.Errorf("Invalid --pivot value %q", spec)
+	}
+	return keys[0], keys[1], nil
+}
+
+// reshapeResults applies --unpivot or --pivot.
+func reshapeResults(c *cli.Context, results []json2csv.KeyValue) ([]json2csv.KeyValue, error) {
+	// validated in app.Before
+	if c.String("unpivot") != "" {
+		keyKey, valueKey, _ := unpivotColumns(c.String("unpivot-columns"))
+		return json2csv.Unpivot(results, c.String("unpivot"), keyKey, valueKey)
+	}
+	if c.String("pivot") != "" {
+		keyKey, valueKey, _ := pivotColumns(c.String("pivot"))
+		return json2csv.Pivot(results, keyKey, valueKey, c.String("pivot-prefix"))
+	}
+	return results, nil
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/yukithm/json2csv"
+	"github.com/yukithm/json2csv/jsonpointer"
+)
+
+var missingOrderTable = map[string]json2csv.MissingOrder{
+	"first": json2csv.MissingFirst,
+	"last":  json2csv.MissingLast,
+}
+
+// parseSortKeys parses --sort-by value, comma separated JSON Pointers.
+// Pointers prefixed with '-' are sorted in descending order.
+func parseSortKeys(spec string) ([]json2csv.SortKey, error) {
+	if spec == "" {
+		return nil, nil
+	}
+
+	var keys []json2csv.SortKey
+	for _, s := range strings.Split(spec, ",") {
+		s = strings.TrimSpace(s)
+		key := json2csv.SortKey{Key: s}
+		if strings.HasPrefix(s, "-") || strings.HasPrefix(s, "+") {
+			key = json2csv.SortKey{Key: s[1:], Descending: s[0] == '-'}
+		}
+		if _, err := jsonpointer.New(key.Key); err != nil || key.Key == "" {
+			return nil, fmt.Errorf("Invalid --sort-by value %q", s)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
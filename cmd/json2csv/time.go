@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/yukithm/json2csv"
+	"github.com/yukithm/json2csv/jsonpointer"
+)
+
+var timeKindTable = map[string]json2csv.TimeKind{
+	"auto":     json2csv.AutoTime,
+	"rfc3339":  json2csv.RFC3339Time,
+	"epoch":    json2csv.EpochSeconds,
+	"epoch_ms": json2csv.EpochMillis,
+	"epoch_us": json2csv.EpochMicros,
+	"epoch_ns": json2csv.EpochNanos,
+}
+
+// parseTimeRules parses --time values.
+func parseTimeRules(specs []string) (map[string]*json2csv.TimeRule, error) {
+	rules := make(map[string]*json2csv.TimeRule, len(specs))
+	for _, spec := range specs {
+		key, rule, err := parseTimeRule(spec)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid --time value %q: %s", spec, err)
+		}
+		rules[key] = rule
+	}
+	return rules, nil
+}
+
+// parseTimeRule parses "POINTER:KIND[:LAYOUT[:TZ]]". The layout may contain
+// colons, so the last part is the time zone only if it is a known location.
+func parseTimeRule(spec string) (string, *json2csv.TimeRule, error) {
+	parts := strings.SplitN(spec, ":", 3)
+	if len(parts) < 2 {
+		return "", nil, fmt.Errorf("kind is required")
+	}
+
+	pointer, err := jsonpointer.New(parts[0])
+	if err != nil {
+		return "", nil, err
+	}
+	kind, ok := timeKindTable[parts[1]]
+	if !ok {
+		return "", nil, fmt.Errorf("unknown kind %q", parts[1])
+	}
+	rule := &json2csv.TimeRule{Kind: kind}
+
+	if len(parts) == 3 {
+		rule.Layout = parts[2]
+		if i := strings.LastIndex(parts[2], ":"); i >= 0 {
+			if loc := loadLocation(parts[2][i+1:]); loc != nil {
+				rule.Layout = parts[2][:i]
+				rule.Location = loc
+			}
+		} else if loc := loadLocation(parts[2]); loc != nil {
+			// no layout
+			rule.Layout = ""
+			rule.Location = loc
+		}
+	}
+	return pointer.String(), rule, nil
+}
+
+// loadLocation returns the location of the name, or nil if the name is not
+// a location. (e.g. "05" in the layout "15:04:05")
+func loadLocation(name string) *time.Location {
+	if strings.IndexFunc(name, unicode.IsLetter) < 0 {
+		return nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil
+	}
+	return loc
+}
@@ -0,0 +1,420 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/yukithm/json2csv"
+	"github.com/yukithm/json2csv/jsonpointer"
+
+	"github.com/urfave/cli"
+)
+
+// parseRenames parses --rename values, POINTER=NAME.
+func parseRenames(specs []string) (map[string]string, error) {
+	if len(specs) == 0 {
+		return nil, nil
+	}
+
+	names := make(map[string]string, len(specs))
+	for _, spec := range specs {
+		i := strings.Index(spec, "=")
+		if i < 0 {
+			return nil, fmt.Errorf("Invalid --rename value %q", spec)
+		}
+		key := strings.TrimSpace(spec[:i])
+		if _, err := jsonpointer.New(key); err != nil || key == "" {
+			return nil, fmt.Errorf("Invalid --rename value %q", spec)
+		}
+		names[key] = spec[i+1:]
+	}
+	return names, nil
+}
+
+// transposeAnnotations returns the columns of --transpose-types and
+// --transpose-descriptions.
+func transposeAnnotations(c *cli.Context, results []json2csv.KeyValue, columns []string) ([]map[string]string, error) {
+	var annotations []map[string]string
+	if c.Bool("transpose-types") {
+		types := make(map[string]string, len(columns))
+		for i, t := range json2csv.InferColumnTypes(results, columns) {
+			types[columns[i]] = t.String()
+		}
+		annotations = append(annotations, types)
+	}
+	if c.String("transpose-descriptions") != "" {
+		descriptions, err := readDescriptionsFile(c.String("transpose-descriptions"))
+		if err != nil {
+			return nil, err
+		}
+		annotations = append(annotations, descriptions)
+	}
+	return annotations, nil
+}
+
+func readDescriptionsFile(filename string) (map[string]string, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var descriptions map[string]string
+	if err := json.Unmarshal(data, &descriptions); err != nil {
+		return nil, fmt.Errorf("%s: %s", filename, err)
+	}
+	for key := range descriptions {
+		if _, err := jsonpointer.New(key); err != nil {
+			return nil, fmt.Errorf("%s: %s", filename, err)
+		}
+	}
+	return descriptions, nil
+}
+
+// writeTransposedFiles writes each block of --transpose-chunk records to a
+// numbered file. All files have the same columns.
+func writeTransposedFiles(c *cli.Context, results []json2csv.KeyValue, headerStyle json2csv.KeyStyle, columns []string) error {
+	w, columns, drift, err := schemaCSVWriter(c, ioutil.Discard, results, headerStyle, columns)
+	if err != nil {
+		return err
+	}
+	w.Columns = columns
+	w.Drift = json2csv.IgnoreDrift
+	w.ChunkSize = 0
+
+	size := c.Int("transpose-chunk")
+	for i := 0; i*size < len(results); i++ {
+		end := (i + 1) * size
+		if end > len(results) {
+			end = len(results)
+		}
+		if err := writeTransposedFile(c, chunkFileName(c.String("output"), i+1), w, results[i*size:end]); err != nil {
+			return err
+		}
+	}
+
+	if len(drift) > 0 {
+		return &json2csv.DriftError{Keys: drift}
+	}
+	return nil
+}
+
+func writeTransposedFile(c *cli.Context, filename string, config *json2csv.CSVWriter, results []json2csv.KeyValue) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	ew, err := encodeWriter(f, c.String("output-encoding"), c.String("unencodable"))
+	if err != nil {
+		return err
+	}
+	if err := csvWriterTo(config, ew).WriteCSV(results); err != nil {
+		return err
+	}
+	if err := ew.Close(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// chunkFileName returns the name of the n-th file, e.g. out-2.csv for out.csv.
+func chunkFileName(output string, n int) string {
+	ext := filepath.Ext(output)
+	return fmt.Sprintf("%s-%d%s", strings.TrimSuffix(output, ext), n, ext)
+}
+
+// writeTransposedStream writes transposed CSV with the columns of --schema-in
+// while reading JSON input, so that only a block of --transpose-chunk records
+// is held in memory. Keys not in the columns are dropped, and reported by
+// *json2csv.DriftError after the output is written.
+//
+// With --dedupe or --sort-by, the blocks are written after all input is read,
+// holding at most --dedupe-buffer digests and --sort-buffer records in memory.
+func writeTransposedStream(c *cli.Context) error {
+	filenames, err := inputFiles(c.Args())
+	if err != nil {
+		return err
+	}
+	columns, err := readColumnsFile(c.String("schema-in"))
+	if err != nil {
+		return err
+	}
+
+	config := newCSVWriter(c, ioutil.Discard, headerStyleTable[c.String("header-style")])
+	config.Columns = columns
+	config.Drift = json2csv.IgnoreDrift
+	config.ChunkSize = 0
+	config.Annotations, err = transposeAnnotations(c, nil, columns)
+	if err != nil {
+		return err
+	}
+
+	stream := newTransposedStream(c, config)
+	err = streamResults(c, filenames, stream.add)
+	if closeErr := stream.close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// streamResults calls fn with the records in the files, deduplicated by
+// --dedupe and sorted by --sort-by. Records are added to them as they are
+// read, and passed to fn after all input is read.
+func streamResults(c *cli.Context, filenames []string, fn func(json2csv.KeyValue) error) error {
+	// validated in app.Before
+	keys, _ := parseSortKeys(c.String("sort-by"))
+	if len(keys) == 0 {
+		return streamDeduped(c, filenames, fn)
+	}
+
+	sorter := json2csv.NewExternalSorter(keys, missingOrderTable[c.String("sort-missing")], c.Int("sort-buffer"))
+	defer sorter.Close()
+	if err := streamDeduped(c, filenames, sorter.Add); err != nil {
+		return err
+	}
+	if err := sorter.Each(fn); err != nil {
+		return err
+	}
+	return sorter.Close()
+}
+
+func streamDeduped(c *cli.Context, filenames []string, fn func(json2csv.KeyValue) error) error {
+	if !c.Bool("dedupe") && c.String("dedupe-key") == "" {
+		return streamRecords(c, filenames, fn)
+	}
+
+	// validated in app.Before
+	keys, _ := parsePointerList("dedupe-key", c.String("dedupe-key"))
+	deduper := &json2csv.Deduper{
+		Keys:       keys,
+		Keep:       dedupeKeepTable[c.String("dedupe-keep")],
+		BufferSize: c.Int("dedupe-buffer"),
+	}
+	defer deduper.Close()
+	if err := streamRecords(c, filenames, deduper.Add); err != nil {
+		return err
+	}
+	if err := deduper.Each(fn); err != nil {
+		return err
+	}
+	return deduper.Close()
+}
+
+// streamRecords converts JSON values in the files one by one with --where,
+// --add-column and --source-column, and calls fn with each record.
+func streamRecords(c *cli.Context, filenames []string, fn func(json2csv.KeyValue) error) error {
+	// validated in app.Before
+	filter, _ := whereFilter(c.String("where"))
+	computed, _ := computedColumns(c.StringSlice("add-column"))
+	converter := &json2csv.Converter{Filter: filter, Computed: computed}
+	sourceKey := sourceColumnKey(c)
+
+	for _, filename := range filenames {
+		format := c.String("input-format")
+		if format == "" {
+			format = detectInputFormat(filename)
+		}
+		if format != jsonFormat {
+			return fmt.Errorf("%s: --transpose-stream supports only JSON input", filename)
+		}
+
+		err := streamJSONFile(filename, func(value interface{}) error {
+			results, err := converter.Convert(value)
+			if err != nil {
+				return err
+			}
+			for _, result := range results {
+				if sourceKey != "" {
+					if err := addSource(result, sourceKey, filename); err != nil {
+						return err
+					}
+				}
+				if err := fn(result); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("%s: %s", filename, err)
+		}
+	}
+	return nil
+}
+
+// transposedStream writes blocks of transposed CSV as records are added.
+type transposedStream struct {
+	c      *cli.Context
+	config *json2csv.CSVWriter
+	fixed  map[string]bool
+	drift  map[string]bool
+	block  []json2csv.KeyValue
+	blocks int
+
+	// output without --transpose-split, opened at the first block
+	out *outputWriter
+	csv *json2csv.CSVWriter
+}
+
+func newTransposedStream(c *cli.Context, config *json2csv.CSVWriter) *transposedStream {
+	fixed := make(map[string]bool, len(config.Columns))
+	for _, key := range config.Columns {
+		fixed[key] = true
+	}
+	return &transposedStream{
+		c:      c,
+		config: config,
+		fixed:  fixed,
+		drift:  map[string]bool{},
+		block:  make([]json2csv.KeyValue, 0, c.Int("transpose-chunk")),
+	}
+}
+
+func (s *transposedStream) add(result json2csv.KeyValue) error {
+	for key := range result {
+		if !s.fixed[key] {
+			s.drift[key] = true
+		}
+	}
+	s.block = append(s.block, result)
+	if len(s.block) >= s.c.Int("transpose-chunk") {
+		return s.flush()
+	}
+	return nil
+}
+
+// flush writes the records added since the last block.
+func (s *transposedStream) flush() error {
+	if len(s.block) == 0 {
+		return nil
+	}
+	s.blocks++
+	defer func() { s.block = s.block[:0] }()
+
+	if s.c.Bool("transpose-split") {
+		return writeTransposedFile(s.c, chunkFileName(s.c.String("output"), s.blocks), s.config, s.block)
+	}
+	if s.out == nil {
+		out, err := openOutput(s.c)
+		if err != nil {
+			return err
+		}
+		s.out = out
+		s.csv = csvWriterTo(s.config, out)
+	}
+	return s.csv.WriteTransposedBlock(s.block, s.blocks == 1)
+}
+
+// close writes the last block and closes the output. It returns
+// *json2csv.DriftError if there were keys not in the columns.
+func (s *transposedStream) close() error {
+	err := s.flush()
+	if s.out != nil {
+		if closeErr := s.out.Close(); err == nil {
+			err = closeErr
+		}
+		s.out = nil
+	}
+	if err == nil && len(s.drift) > 0 {
+		keys := make([]string, 0, len(s.drift))
+		for key := range s.drift {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		err = &json2csv.DriftError{Keys: keys}
+	}
+	return err
+}
+
+// streamJSONFile decodes JSON values in the file (or STDIN) one by one and
+// calls fn with each of them. Elements of top-level arrays are passed
+// instead of the arrays, so that records in an array are not read at once.
+func streamJSONFile(filename string, fn func(interface{}) error) error {
+	var in io.Reader = os.Stdin
+	if filename != stdinName {
+		f, err := os.Open(filename)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+	r, err := decompressReader(in, filename)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	br := bufio.NewReader(decodeBOM(r))
+	decoder := json.NewDecoder(br)
+	decoder.UseNumber()
+	for {
+		b, err := nextJSONByte(decoder, br)
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if b != '[' {
+			var value interface{}
+			if err := decoder.Decode(&value); err != nil {
+				return err
+			}
+			if err := fn(value); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if _, err := decoder.Token(); err != nil {
+			return err
+		}
+		for decoder.More() {
+			var value interface{}
+			if err := decoder.Decode(&value); err != nil {
+				return err
+			}
+			if err := fn(value); err != nil {
+				return err
+			}
+		}
+		if _, err := decoder.Token(); err != nil {
+			return err
+		}
+	}
+}
+
+// nextJSONByte returns the next non-space byte of the input of the decoder
+// without consuming it. br must be the reader of the decoder.
+func nextJSONByte(decoder *json.Decoder, br *bufio.Reader) (byte, error) {
+	buffered, _ := ioutil.ReadAll(decoder.Buffered())
+	for _, b := range buffered {
+		if !isJSONSpace(b) {
+			return b, nil
+		}
+	}
+	// Spaces in br can be skipped since the decoder ignores them.
+	for {
+		head, err := br.Peek(1)
+		if err != nil {
+			return 0, err
+		}
+		if !isJSONSpace(head[0]) {
+			return head[0], nil
+		}
+		br.ReadByte()
+	}
+}
+
+func isJSONSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r'
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/yukithm/json2csv"
+
+	"github.com/urfave/cli"
+)
+
+// runApp runs the application with the arguments, calling action instead of
+// mainAction after the options are validated.
+func runApp(args []string, action func(c *cli.Context) error) error {
+	app := newApp()
+	app.Writer = ioutil.Discard
+	var err error
+	app.Action = func(c *cli.Context) {
+		err = action(c)
+	}
+	if runErr := app.Run(append([]string{ApplicationName}, args...)); runErr != nil {
+		return runErr
+	}
+	return err
+}
+
+// writeTestFiles writes the files into a temporary directory, and returns the
+// directory.
+func writeTestFiles(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "json2csv-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			os.RemoveAll(dir)
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func readTestFile(t *testing.T, filename string) string {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestWriteTransposedStreamSorted(t *testing.T) {
+	dir := writeTestFiles(t, map[string]string{
+		"cols.json": `{"columns": ["/id", "/name"]}`,
+		"in.jsonl":  "{\"id\": 3, \"name\": \"c\"}\n{\"id\": 1, \"name\": \"a\"}\n{\"id\": 3, \"name\": \"x\"}\n",
+		"in2.jsonl": "[{\"id\": 5, \"name\": \"e\"}, {\"id\": 2, \"name\": \"b\"}]\n{\"id\": 4}\n{\"id\": 1, \"name\": \"a\"}\n",
+	})
+	defer os.RemoveAll(dir)
+
+	var testCases = []struct {
+		args     []string
+		expected string
+	}{
+		{
+			[]string{"--sort-by=-/id"},
+			"/id,5,4\n/name,e,\n\n/id,3,3\n/name,c,x\n\n/id,2,1\n/name,b,a\n\n/id,1\n/name,a\n",
+		},
+		{
+			[]string{"--sort-by=-/id", "--sort-buffer=1"},
+			"/id,5,4\n/name,e,\n\n/id,3,3\n/name,c,x\n\n/id,2,1\n/name,b,a\n\n/id,1\n/name,a\n",
+		},
+		{
+			[]string{"--sort-by=-/id", "--sort-buffer=2", "--dedupe"},
+			"/id,5,4\n/name,e,\n\n/id,3,3\n/name,c,x\n\n/id,2,1\n/name,b,a\n",
+		},
+		{
+			[]string{"--dedupe-key=/id"},
+			"/id,3,1\n/name,c,a\n\n/id,5,2\n/name,e,b\n\n/id,4\n/name,\n",
+		},
+		{
+			[]string{"--dedupe-key=/id", "--dedupe-keep=last", "--dedupe-buffer=2"},
+			"/id,3,5\n/name,x,e\n\n/id,2,4\n/name,b,\n\n/id,1\n/name,a\n",
+		},
+		{
+			[]string{"--dedupe-key=/id", "--dedupe-buffer=1", "--sort-by=/name", "--sort-buffer=3"},
+			"/id,1,2\n/name,a,b\n\n/id,3,5\n/name,c,e\n\n/id,4\n/name,\n",
+		},
+	}
+
+	for _, testCase := range testCases {
+		output := filepath.Join(dir, "out.csv")
+		args := append([]string{
+			"--transpose", "--transpose-chunk=2", "--transpose-stream",
+			"--schema-in=" + filepath.Join(dir, "cols.json"),
+			"--output=" + output,
+		}, testCase.args...)
+		args = append(args, filepath.Join(dir, "in.jsonl"), filepath.Join(dir, "in2.jsonl"))
+		if err := runApp(args, writeTransposedStream); err != nil {
+			t.Errorf("%v: %v", testCase.args, err)
+			continue
+		}
+		if actual := readTestFile(t, output); actual != testCase.expected {
+			t.Errorf("%v: Expected %q, but %q", testCase.args, testCase.expected, actual)
+		}
+	}
+}
+
+func TestChunkFileName(t *testing.T) {
+	var testCases = []struct {
+		output   string
+		expected string
+	}{
+		{"out.csv", "out-2.csv"},
+		{"out", "out-2"},
+		{"dir/out.csv", "dir/out-2.csv"},
+		{"dir.d/out", "dir.d/out-2"},
+		{"out.tsv.txt", "out.tsv-2.txt"},
+	}
+
+	for _, testCase := range testCases {
+		if actual := chunkFileName(testCase.output, 2); actual != testCase.expected {
+			t.Errorf("%s: Expected %q, but %q", testCase.output, testCase.expected, actual)
+		}
+	}
+}
+
+func TestStreamJSONFile(t *testing.T) {
+	dir := writeTestFiles(t, map[string]string{
+		"in.json": `{"id": 1} [{"id": 2}, {"id": 3}]
+[]
+{"id": 4}[{"id": 5}][[6, 7]]  8`,
+	})
+	defer os.RemoveAll(dir)
+
+	var actual []interface{}
+	err := streamJSONFile(filepath.Join(dir, "in.json"), func(value interface{}) error {
+		actual = append(actual, value)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []interface{}{
+		map[string]interface{}{"id": json.Number("1")},
+		map[string]interface{}{"id": json.Number("2")},
+		map[string]interface{}{"id": json.Number("3")},
+		map[string]interface{}{"id": json.Number("4")},
+		map[string]interface{}{"id": json.Number("5")},
+		[]interface{}{json.Number("6"), json.Number("7")},
+		json.Number("8"),
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected %v, but %v", expected, actual)
+	}
+
+	for _, content := range []string{`[{"id": 1}`, `{"id": 1} [{"id": 2},`, `{"id": 1} }`} {
+		filename := filepath.Join(dir, "invalid.json")
+		if err := ioutil.WriteFile(filename, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		err := streamJSONFile(filename, func(value interface{}) error { return nil })
+		if err == nil {
+			t.Errorf("%s: Expected error", content)
+		}
+	}
+}
+
+func TestTransposeSplit(t *testing.T) {
+	dir := writeTestFiles(t, map[string]string{
+		"cols.json": `{"columns": ["/id", "/name"]}`,
+		"in.jsonl":  "{\"id\": 1, \"name\": \"a\"}\n[{\"id\": 2, \"name\": \"b\"}, {\"id\": 3, \"extra\": true}]\n",
+	})
+	defer os.RemoveAll(dir)
+
+	for _, output := range []string{"", "-"} {
+		args := []string{"--transpose", "--transpose-chunk=2", "--transpose-split", "--output=" + output, filepath.Join(dir, "in.jsonl")}
+		err := runApp(args, func(c *cli.Context) error {
+			t.Errorf("%q: Expected error for the output", output)
+			return nil
+		})
+		if err == nil {
+			t.Errorf("%q: Expected error", output)
+		}
+	}
+
+	// the blocks are written before *json2csv.DriftError is returned
+	results := []json2csv.KeyValue{
+		{"/id": json.Number("1"), "/name": "a"},
+		{"/id": json.Number("2"), "/name": "b"},
+		{"/id": json.Number("3"), "/extra": true},
+	}
+	var testCases = []struct {
+		name   string
+		args   []string
+		action func(c *cli.Context) error
+	}{
+		{"out", []string{"--transpose-stream"}, writeTransposedStream},
+		{"out.csv", []string{"--on-drift=warn"}, func(c *cli.Context) error {
+			return writeResults(c, results, nil)
+		}},
+	}
+
+	for _, testCase := range testCases {
+		output := filepath.Join(dir, testCase.name)
+		args := append([]string{
+			"--transpose", "--transpose-chunk=2", "--transpose-split",
+			"--schema-in=" + filepath.Join(dir, "cols.json"),
+			"--output=" + output,
+		}, testCase.args...)
+		args = append(args, filepath.Join(dir, "in.jsonl"))
+		err := runApp(args, testCase.action)
+		if driftErr, ok := err.(*json2csv.DriftError); !ok {
+			t.Errorf("%s: Expected *json2csv.DriftError, but %v", testCase.name, err)
+		} else if !reflect.DeepEqual(driftErr.Keys, []string{"/extra"}) {
+			t.Errorf("%s: Expected /extra, but %v", testCase.name, driftErr.Keys)
+		}
+
+		expected := []string{"/id,1,2\n/name,a,b\n", "/id,3\n/name,\n"}
+		for i, content := range expected {
+			filename := chunkFileName(output, i+1)
+			if actual := readTestFile(t, filename); actual != content {
+				t.Errorf("%s: Expected %q, but %q", filename, content, actual)
+			}
+		}
+		if _, err := os.Stat(chunkFileName(output, 3)); !os.IsNotExist(err) {
+			t.Errorf("%s: Expected no third file, but %v", testCase.name, err)
+		}
+		if _, err := os.Stat(output); !os.IsNotExist(err) {
+			t.Errorf("%s: Expected %s not to be written, but %v", testCase.name, output, err)
+		}
+	}
+}
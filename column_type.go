@@ -0,0 +1,116 @@
+package json2csv
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+)
+
+// ColumnType represents the type of the column inferred from its values.
+type ColumnType uint
+
+// Column types
+const (
+	// No values except null.
+	UnknownColumn ColumnType = iota
+
+	// Only true/false.
+	BooleanColumn
+
+	// Only integral numbers.
+	IntegerColumn
+
+	// Only integral numbers, at least one of them is out of the range of int64.
+	BigIntegerColumn
+
+	// Numbers, at least one of them is not integral.
+	FloatColumn
+
+	// Anything else.
+	TextColumn
+)
+
+// String returns the name of the column type.
+func (t ColumnType) String() string {
+	switch t {
+	case BooleanColumn:
+		return "boolean"
+	case IntegerColumn:
+		return "integer"
+	case BigIntegerColumn:
+		return "biginteger"
+	case FloatColumn:
+		return "float"
+	case TextColumn:
+		return "text"
+	default:
+		return "unknown"
+	}
+}
+
+// InferColumnTypes returns the column type for each key.
+func InferColumnTypes(results []KeyValue, keys []string) []ColumnType {
+	types := make([]ColumnType, len(keys))
+	for i, key := range keys {
+		for _, result := range results {
+			if value, ok := result[key]; ok {
+				types[i] = mergeColumnType(types[i], valueColumnType(value))
+			}
+		}
+	}
+	return types
+}
+
+func valueColumnType(value interface{}) ColumnType {
+	switch v := value.(type) {
+	case nil:
+		return UnknownColumn
+	case bool:
+		return BooleanColumn
+	case int64:
+		return IntegerColumn
+	case uint64:
+		if v > math.MaxInt64 {
+			return BigIntegerColumn
+		}
+		return IntegerColumn
+	case float64:
+		return FloatColumn
+	case json.Number:
+		return numberColumnType(v)
+	default:
+		return TextColumn
+	}
+}
+
+func numberColumnType(n json.Number) ColumnType {
+	if strings.ContainsAny(n.String(), ".eE") {
+		return FloatColumn
+	}
+	if _, err := n.Int64(); err != nil {
+		return BigIntegerColumn
+	}
+	return IntegerColumn
+}
+
+func isNumberColumn(t ColumnType) bool {
+	return t == IntegerColumn || t == BigIntegerColumn || t == FloatColumn
+}
+
+func mergeColumnType(a, b ColumnType) ColumnType {
+	switch {
+	case a == b:
+		return a
+	case a == UnknownColumn:
+		return b
+	case b == UnknownColumn:
+		return a
+	case isNumberColumn(a) && isNumberColumn(b):
+		if a == FloatColumn || b == FloatColumn {
+			return FloatColumn
+		}
+		return BigIntegerColumn
+	default:
+		return TextColumn
+	}
+}
@@ -2,8 +2,12 @@ package json2csv
 
 import (
 	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"sort"
+	"strings"
 
 	"github.com/yukithm/json2csv/jsonpointer"
 )
@@ -26,20 +30,125 @@ const (
 	DotBracketStyle
 )
 
+// DriftPolicy represents the behavior when results have keys that are not
+// in the fixed columns.
+type DriftPolicy uint
+
+// Drift policies
+const (
+	// Return *DriftError without writing anything.
+	ErrorOnDrift DriftPolicy = iota
+
+	// Drop the new keys.
+	IgnoreDrift
+
+	// Append the new keys after the fixed columns.
+	AppendDrift
+)
+
+// DriftError is returned when results have keys that are not in the fixed columns.
+type DriftError struct {
+	Keys []string
+}
+
+func (e *DriftError) Error() string {
+	return fmt.Sprintf("Found new columns: %s", strings.Join(e.Keys, ", "))
+}
+
+// CellFormatter converts the value to the cell string.
+type CellFormatter func(value interface{}) string
+
 // CSVWriter writes CSV data.
 type CSVWriter struct {
 	*csv.Writer
 	HeaderStyle KeyStyle
 	Transpose   bool
+
+	// Columns fixes keys (JSON Pointers) and order of the columns.
+	// If nil, all keys in the results are used.
+	Columns []string
+	Drift   DriftPolicy
+
+	// Formatters formats cells of the column specified by the key.
+	Formatters map[string]CellFormatter
+
+	// ValueFormatter formats cells of columns without Formatters.
+	ValueFormatter ValueFormatter
+
+	// SafeSpreadsheet neutralizes headers and cells that spreadsheet
+	// applications would evaluate as formulas (starting with '=', '+', '-',
+	// '@', tab or carriage return) by prepending FormulaPrefix.
+	// Numbers such as json.Number are not changed since they are known numbers.
+	SafeSpreadsheet bool
+	FormulaPrefix   string
+
+	// HeaderNames renames headers of the keys. (e.g. "/user/name": "Name")
+	HeaderNames map[string]string
+
+	// ChunkSize is the maximum number of records per block of transposed
+	// CSV. Blocks are separated by an empty line and each has the header
+	// column. 0 means all records in a block.
+	ChunkSize int
+
+	// Annotations are columns after the header column of transposed CSV,
+	// such as types or descriptions of the keys.
+	Annotations []map[string]string
 }
 
-// NewCSVWriter returns new CSVWriter with JSONPointerStyle.
+// DefaultFormulaPrefix is the default FormulaPrefix recommended by OWASP.
+const DefaultFormulaPrefix = "'"
+
+// NewCSVWriter returns new CSVWriter with JSONPointerStyle and
+// StandardValueFormatter.
 func NewCSVWriter(w io.Writer) *CSVWriter {
 	return &CSVWriter{
-		csv.NewWriter(w),
-		JSONPointerStyle,
-		false,
+		Writer:         csv.NewWriter(w),
+		HeaderStyle:    JSONPointerStyle,
+		Drift:          ErrorOnDrift,
+		ValueFormatter: NewStandardValueFormatter(),
+		FormulaPrefix:  DefaultFormulaPrefix,
+	}
+}
+
+// ResolveColumns returns keys of the columns to be written, and keys in the
+// results that are not in w.Columns.
+func (w *CSVWriter) ResolveColumns(results []KeyValue) (columns []string, drift []string, err error) {
+	pts, err := sortedPointers(results)
+	if err != nil {
+		return nil, nil, err
 	}
+	if w.Columns == nil {
+		return pts.Strings(), nil, nil
+	}
+
+	fixed := make(map[string]bool, len(w.Columns))
+	for _, key := range w.Columns {
+		fixed[key] = true
+	}
+	for _, key := range pts.Strings() {
+		if !fixed[key] {
+			drift = append(drift, key)
+		}
+	}
+
+	columns = append([]string{}, w.Columns...)
+	if len(drift) > 0 {
+		switch w.Drift {
+		case ErrorOnDrift:
+			return nil, drift, &DriftError{drift}
+		case AppendDrift:
+			columns = append(columns, drift...)
+		}
+	}
+	return columns, drift, nil
+}
+
+func (w *CSVWriter) resolvePointers(results []KeyValue) (pointers, error) {
+	keys, _, err := w.ResolveColumns(results)
+	if err != nil {
+		return nil, err
+	}
+	return pointersOf(keys)
 }
 
 // WriteCSV writes CSV data.
@@ -52,11 +161,10 @@ func (w *CSVWriter) WriteCSV(results []KeyValue) error {
 
 // WriteCSV writes CSV data.
 func (w *CSVWriter) writeCSV(results []KeyValue) error {
-	pts, err := allPointers(results)
+	pts, err := w.resolvePointers(results)
 	if err != nil {
 		return err
 	}
-	sort.Sort(pts)
 	keys := pts.Strings()
 	header := w.getHeader(pts)
 
@@ -65,7 +173,7 @@ func (w *CSVWriter) writeCSV(results []KeyValue) error {
 	}
 
 	for _, result := range results {
-		record := toRecord(result, keys)
+		record := w.toRecord(result, keys)
 		if err := w.Write(record); err != nil {
 			return err
 		}
@@ -81,27 +189,84 @@ func (w *CSVWriter) writeCSV(results []KeyValue) error {
 
 // WriteCSV writes CSV data which is transposed rows and columns.
 func (w *CSVWriter) writeTransposedCSV(results []KeyValue) error {
-	pts, err := allPointers(results)
+	pts, err := w.resolvePointers(results)
 	if err != nil {
 		return err
 	}
-	sort.Sort(pts)
-	keys := pts.Strings()
-	header := w.getHeader(pts)
 
-	for i, key := range keys {
-		record := toTransposedRecord(results, key, header[i])
+	size := w.ChunkSize
+	if size <= 0 || size > len(results) {
+		size = len(results)
+	}
+	for start := 0; start == 0 || start < len(results); start += size {
+		end := start + size
+		if end > len(results) {
+			end = len(results)
+		}
+		if err := w.writeTransposedBlock(pts, results[start:end], start == 0); err != nil {
+			return err
+		}
+		if size == 0 {
+			break
+		}
+	}
+	return nil
+}
+
+// WriteTransposedBlock writes the results as a block of transposed CSV with
+// the keys in Columns, which must be fixed. The block is preceded by an empty
+// line unless it is the first one, and keys not in Columns are not written.
+// It writes transposed CSV of records read block by block, without holding
+// all of them.
+func (w *CSVWriter) WriteTransposedBlock(results []KeyValue, first bool) error {
+	if w.Columns == nil {
+		return errors.New("Columns must be fixed to write transposed blocks")
+	}
+	pts, err := pointersOf(w.Columns)
+	if err != nil {
+		return err
+	}
+	return w.writeTransposedBlock(pts, results, first)
+}
+
+func (w *CSVWriter) writeTransposedBlock(pts pointers, results []KeyValue, first bool) error {
+	if !first {
+		// empty line between blocks
+		if err := w.Write(nil); err != nil {
+			return err
+		}
+	}
+	header := w.getHeader(pts)
+	for i, key := range pts.Strings() {
+		record := w.toTransposedRecord(results, key, header[i])
 		if err := w.Write(record); err != nil {
 			return err
 		}
 	}
 
 	w.Flush()
-	if err := w.Error(); err != nil {
-		return err
+	return w.Error()
+}
+
+func sortedPointers(results []KeyValue) (pointers, error) {
+	pts, err := allPointers(results)
+	if err != nil {
+		return nil, err
 	}
+	sort.Sort(pts)
+	return pts, nil
+}
 
-	return nil
+func pointersOf(keys []string) (pointers, error) {
+	pts := make(pointers, 0, len(keys))
+	for _, key := range keys {
+		pointer, err := jsonpointer.New(key)
+		if err != nil {
+			return nil, err
+		}
+		pts = append(pts, pointer)
+	}
+	return pts, nil
 }
 
 func allPointers(results []KeyValue) (pointers pointers, err error) {
@@ -122,7 +287,22 @@ func allPointers(results []KeyValue) (pointers pointers, err error) {
 }
 
 func (w *CSVWriter) getHeader(pointers pointers) []string {
-	switch w.HeaderStyle {
+	header := headerOf(pointers, w.HeaderStyle)
+	for i, pointer := range pointers {
+		if name, ok := w.HeaderNames[pointer.String()]; ok {
+			header[i] = name
+		}
+	}
+	if w.SafeSpreadsheet {
+		for i, name := range header {
+			header[i] = w.neutralize(name)
+		}
+	}
+	return header
+}
+
+func headerOf(pointers pointers, style KeyStyle) []string {
+	switch style {
 	case JSONPointerStyle:
 		return pointers.Strings()
 	case SlashStyle:
@@ -136,11 +316,45 @@ func (w *CSVWriter) getHeader(pointers pointers) []string {
 	}
 }
 
-func toRecord(kv KeyValue, keys []string) []string {
+func (w *CSVWriter) formatCell(key string, value interface{}) string {
+	var cell string
+	if format, ok := w.Formatters[key]; ok {
+		cell = format(value)
+	} else if w.ValueFormatter != nil {
+		cell = w.ValueFormatter.FormatValue(value)
+	} else {
+		cell = toString(value)
+	}
+
+	if w.SafeSpreadsheet && !isNumberValue(value) {
+		cell = w.neutralize(cell)
+	}
+	return cell
+}
+
+// neutralize prepends FormulaPrefix to the cell if it would be evaluated as
+// a formula.
+func (w *CSVWriter) neutralize(cell string) string {
+	if cell != "" && strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+		return w.FormulaPrefix + cell
+	}
+	return cell
+}
+
+func isNumberValue(value interface{}) bool {
+	switch value.(type) {
+	case json.Number, int64, uint64, float64:
+		return true
+	default:
+		return false
+	}
+}
+
+func (w *CSVWriter) toRecord(kv KeyValue, keys []string) []string {
 	record := make([]string, 0, len(keys))
 	for _, key := range keys {
 		if value, ok := kv[key]; ok {
-			record = append(record, toString(value))
+			record = append(record, w.formatCell(key, value))
 		} else {
 			record = append(record, "")
 		}
@@ -148,12 +362,19 @@ func toRecord(kv KeyValue, keys []string) []string {
 	return record
 }
 
-func toTransposedRecord(results []KeyValue, key string, header string) []string {
-	record := make([]string, 0, len(results)+1)
+func (w *CSVWriter) toTransposedRecord(results []KeyValue, key string, header string) []string {
+	record := make([]string, 0, len(results)+len(w.Annotations)+1)
 	record = append(record, header)
+	for _, annotations := range w.Annotations {
+		annotation := annotations[key]
+		if w.SafeSpreadsheet {
+			annotation = w.neutralize(annotation)
+		}
+		record = append(record, annotation)
+	}
 	for _, result := range results {
 		if value, ok := result[key]; ok {
-			record = append(record, toString(value))
+			record = append(record, w.formatCell(key, value))
 		} else {
 			record = append(record, "")
 		}
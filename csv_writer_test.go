@@ -2,6 +2,7 @@ package json2csv_test
 
 import (
 	"bytes"
+	"encoding/json"
 	"testing"
 
 	"github.com/yukithm/json2csv"
@@ -40,3 +41,150 @@ func TestKeyWithTrailingSpace(t *testing.T) {
 		t.Errorf("Expected %v, but %v", want, got)
 	}
 }
+
+func TestColumns(t *testing.T) {
+	results := []json2csv.KeyValue{
+		{"/id": 1, "/name": "foo"},
+		{"/id": 2, "/name": "bar", "/age": 20},
+	}
+
+	testCases := []struct {
+		drift json2csv.DriftPolicy
+		want  string
+		err   string
+	}{
+		{json2csv.ErrorOnDrift, ``, `Found new columns: /age`},
+		{json2csv.IgnoreDrift, "/name,/id,/missing\nfoo,1,\nbar,2,\n", ``},
+		{json2csv.AppendDrift, "/name,/id,/missing,/age\nfoo,1,,\nbar,2,,20\n", ``},
+	}
+
+	for caseIndex, testCase := range testCases {
+		b := &bytes.Buffer{}
+		wr := json2csv.NewCSVWriter(b)
+		wr.Columns = []string{"/name", "/id", "/missing"}
+		wr.Drift = testCase.drift
+
+		err := wr.WriteCSV(results)
+		if err != nil {
+			if err.Error() != testCase.err {
+				t.Errorf("%d: Expected %v, but %v", caseIndex, testCase.err, err)
+			}
+			if _, ok := err.(*json2csv.DriftError); !ok {
+				t.Errorf("%d: Expected *DriftError, but %T", caseIndex, err)
+			}
+		} else if got := b.String(); got != testCase.want {
+			t.Errorf("%d: Expected %v, but %v", caseIndex, testCase.want, got)
+		}
+	}
+}
+
+func TestSafeSpreadsheet(t *testing.T) {
+	results := []json2csv.KeyValue{
+		{"/=cmd": "=1+2", "/a": "+SUM(A1)", "/b": json.Number("-5"), "/c": "-5", "/d": "@x", "/e": "\tx", "/f": "a=b"},
+	}
+
+	testCases := []struct {
+		prefix string
+		style  json2csv.KeyStyle
+		want   string
+	}{
+		{json2csv.DefaultFormulaPrefix, json2csv.JSONPointerStyle, "/=cmd,/a,/b,/c,/d,/e,/f\n'=1+2,'+SUM(A1),-5,'-5,'@x,'\tx,a=b\n"},
+		{"\t", json2csv.DotNotationStyle, "\"\t=cmd\",a,b,c,d,e,f\n\"\t=1+2\",\"\t+SUM(A1)\",-5,\"\t-5\",\"\t@x\",\"\t\tx\",a=b\n"},
+	}
+
+	for caseIndex, testCase := range testCases {
+		b := &bytes.Buffer{}
+		wr := json2csv.NewCSVWriter(b)
+		wr.HeaderStyle = testCase.style
+		wr.SafeSpreadsheet = true
+		wr.FormulaPrefix = testCase.prefix
+		if err := wr.WriteCSV(results); err != nil {
+			t.Fatal(err)
+		}
+		if got := b.String(); got != testCase.want {
+			t.Errorf("%d: Expected %q, but %q", caseIndex, testCase.want, got)
+		}
+	}
+}
+
+func TestTransposedChunks(t *testing.T) {
+	results := []json2csv.KeyValue{
+		{"/id": 1, "/name": "foo"},
+		{"/id": 2, "/name": "bar"},
+		{"/id": 3, "/name": "baz", "/age": 20},
+	}
+
+	testCases := []struct {
+		chunkSize   int
+		headerNames map[string]string
+		annotations []map[string]string
+		want        string
+	}{
+		{0, nil, nil, "/age,,,20\n/id,1,2,3\n/name,foo,bar,baz\n"},
+		{5, nil, nil, "/age,,,20\n/id,1,2,3\n/name,foo,bar,baz\n"},
+		{2, nil, nil, "/age,,\n/id,1,2\n/name,foo,bar\n\n/age,20\n/id,3\n/name,baz\n"},
+		{1, map[string]string{"/id": "ID", "/age": "Age"}, nil, "Age,\nID,1\n/name,foo\n\nAge,\nID,2\n/name,bar\n\nAge,20\nID,3\n/name,baz\n"},
+		{
+			2,
+			nil,
+			[]map[string]string{{"/id": "integer", "/name": "text", "/age": "integer"}, {"/id": "Identifier"}},
+			"/age,integer,,,\n/id,integer,Identifier,1,2\n/name,text,,foo,bar\n\n/age,integer,,20\n/id,integer,Identifier,3\n/name,text,,baz\n",
+		},
+	}
+
+	for caseIndex, testCase := range testCases {
+		b := &bytes.Buffer{}
+		wr := json2csv.NewCSVWriter(b)
+		wr.Transpose = true
+		wr.ChunkSize = testCase.chunkSize
+		wr.HeaderNames = testCase.headerNames
+		wr.Annotations = testCase.annotations
+		if err := wr.WriteCSV(results); err != nil {
+			t.Fatal(err)
+		}
+		if got := b.String(); got != testCase.want {
+			t.Errorf("%d: Expected %q, but %q", caseIndex, testCase.want, got)
+		}
+	}
+}
+
+func TestWriteTransposedBlock(t *testing.T) {
+	b := &bytes.Buffer{}
+	wr := json2csv.NewCSVWriter(b)
+	if err := wr.WriteTransposedBlock(nil, true); err == nil {
+		t.Error("Expected error without Columns")
+	}
+
+	wr.Columns = []string{"/name", "/id"}
+	blocks := [][]json2csv.KeyValue{
+		{{"/id": 1, "/name": "foo"}, {"/id": 2, "/name": "bar", "/age": 20}},
+		{{"/id": 3}},
+	}
+	for i, block := range blocks {
+		if err := wr.WriteTransposedBlock(block, i == 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := "/name,foo,bar\n/id,1,2\n\n/name,\n/id,3\n"
+	if got := b.String(); got != want {
+		t.Errorf("Expected %q, but %q", want, got)
+	}
+}
+
+func TestHeaderNames(t *testing.T) {
+	results := []json2csv.KeyValue{
+		{"/id": 1, "/user/name": "foo"},
+	}
+
+	b := &bytes.Buffer{}
+	wr := json2csv.NewCSVWriter(b)
+	wr.HeaderStyle = json2csv.DotNotationStyle
+	wr.HeaderNames = map[string]string{"/user/name": "Name"}
+	if err := wr.WriteCSV(results); err != nil {
+		t.Fatal(err)
+	}
+	want := "id,Name\n1,foo\n"
+	if got := b.String(); got != want {
+		t.Errorf("Expected %q, but %q", want, got)
+	}
+}
@@ -0,0 +1,208 @@
+package json2csv
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"hash"
+	"math/big"
+	"sort"
+)
+
+// DedupeKeep represents which record of duplicates is kept.
+type DedupeKeep uint
+
+// Dedupe keeps
+const (
+	KeepFirst DedupeKeep = iota
+	KeepLast
+)
+
+// DedupeResults removes duplicate results and returns the rest in the input
+// order. If keys is empty, results whose all keys and values are identical
+// are duplicates, otherwise results with equal values of the keys (JSON
+// Pointers) are duplicates. Results without any of the keys are always kept.
+// Numbers are equal if their values are equal (e.g. 1 and 1.0).
+//
+// Duplicates are found by SHA-256 digests of results held in memory. Use
+// Deduper with BufferSize to spill them to temporary files.
+func DedupeResults(results []KeyValue, keys []string, keep DedupeKeep) []KeyValue {
+	// Deduper never fails without BufferSize.
+	deduped, _ := (&Deduper{Keys: keys, Keep: keep}).Dedupe(results)
+	return deduped
+}
+
+// Deduper removes duplicate results in the same way as DedupeResults, while
+// the results are added one by one.
+//
+// Add the results with Add, read the rest in the input order with Each, and
+// remove the temporary files with Close.
+type Deduper struct {
+	Keys []string
+	Keep DedupeKeep
+
+	// BufferSize is the number of digests held in memory. If it is positive,
+	// the results are written to a temporary file, and the digests are sorted
+	// in runs of BufferSize, which are written to temporary files and merged.
+	// Zero or negative means that the results and the digests are held in
+	// memory.
+	BufferSize int
+
+	// TempDir is the directory of the temporary files. The default directory
+	// for temporary files is used if it is empty.
+	TempDir string
+
+	h hash.Hash
+
+	// in memory; results dropped by KeepLast are nil
+	results []KeyValue
+	seen    map[digest]int
+
+	// on disk
+	spill *dedupeSpill
+}
+
+type digest [sha256.Size]byte
+
+// Dedupe removes duplicate results and returns the rest in the input order.
+func (d *Deduper) Dedupe(results []KeyValue) ([]KeyValue, error) {
+	defer d.Close()
+	for _, result := range results {
+		if err := d.Add(result); err != nil {
+			return nil, err
+		}
+	}
+	deduped := make([]KeyValue, 0, len(results))
+	err := d.Each(func(result KeyValue) error {
+		deduped = append(deduped, result)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return deduped, nil
+}
+
+// Add adds the result.
+func (d *Deduper) Add(result KeyValue) error {
+	if d.h == nil {
+		d.h = sha256.New()
+	}
+	if d.BufferSize > 0 {
+		return d.addOnDisk(result)
+	}
+
+	if sum, ok := d.digest(d.h, result); ok {
+		if d.seen == nil {
+			d.seen = map[digest]int{}
+		}
+		if j, found := d.seen[sum]; found {
+			if d.Keep == KeepFirst {
+				return nil
+			}
+			d.results[j] = nil
+		}
+		d.seen[sum] = len(d.results)
+	}
+	d.results = append(d.results, result)
+	return nil
+}
+
+// Each calls fn for each result that is kept, in the input order. It can be
+// called only once.
+func (d *Deduper) Each(fn func(KeyValue) error) error {
+	if d.BufferSize > 0 {
+		return d.eachOnDisk(fn)
+	}
+	for _, result := range d.results {
+		if result == nil {
+			continue
+		}
+		if err := fn(result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close removes the temporary files.
+func (d *Deduper) Close() error {
+	d.results = nil
+	d.seen = nil
+	if d.spill == nil {
+		return nil
+	}
+	err := d.spill.close()
+	d.spill = nil
+	return err
+}
+
+// digest returns the digest of the result. It returns false if the result
+// does not have any of the keys.
+func (d *Deduper) digest(h hash.Hash, result KeyValue) (digest, bool) {
+	h.Reset()
+	if len(d.Keys) == 0 {
+		hashRecord(h, result)
+	} else if !hashKeys(h, result, d.Keys) {
+		return digest{}, false
+	}
+	var sum digest
+	h.Sum(sum[:0])
+	return sum, true
+}
+
+func hashRecord(h hash.Hash, result KeyValue) {
+	keys := result.Keys()
+	sort.Strings(keys)
+	for _, key := range keys {
+		hashString(h, key)
+		hashValue(h, result[key], true)
+	}
+}
+
+// hashKeys hashes values of the keys. It returns false if the result does
+// not have any of the keys.
+func hashKeys(h hash.Hash, result KeyValue, keys []string) bool {
+	found := false
+	for _, key := range keys {
+		value, ok := result[key]
+		hashValue(h, value, ok)
+		found = found || ok
+	}
+	return found
+}
+
+// hashValue writes the type and the canonical form of the value.
+func hashValue(h hash.Hash, value interface{}, ok bool) {
+	if !ok {
+		h.Write([]byte{0})
+		return
+	}
+	switch jsonTypeOf(value) {
+	case "null":
+		h.Write([]byte{1})
+	case "boolean":
+		if value.(bool) {
+			h.Write([]byte{2, 1})
+		} else {
+			h.Write([]byte{2, 0})
+		}
+	case "number":
+		h.Write([]byte{3})
+		n := toNumber(value).String()
+		if r, ok := new(big.Rat).SetString(n); ok {
+			n = r.RatString()
+		}
+		hashString(h, n)
+	default:
+		h.Write([]byte{4})
+		hashString(h, toString(value))
+	}
+}
+
+// hashString writes the length-prefixed string, so that concatenations
+// are not ambiguous.
+func hashString(h hash.Hash, s string) {
+	var n [binary.MaxVarintLen64]byte
+	h.Write(n[:binary.PutUvarint(n[:], uint64(len(s)))])
+	h.Write([]byte(s))
+}
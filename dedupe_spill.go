@@ -0,0 +1,208 @@
+package json2csv
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/gob"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+// dedupeSpill holds the state of Deduper with BufferSize. The results are
+// written to a temporary file in the input order, and the indexes of the
+// results to be kept are found by merging the sorted runs of the digests.
+type dedupeSpill struct {
+	records *os.File
+	w       *bufio.Writer
+	enc     *gob.Encoder
+	count   uint64
+
+	// digests are digestEntry, and kept are the indexes (uint64) of the
+	// results to be kept.
+	digests *spilledRuns
+	kept    *spilledRuns
+}
+
+// digestEntry is the digest of the result at the index.
+type digestEntry struct {
+	digest digest
+	index  uint64
+}
+
+const digestEntrySize = sha256.Size + 8
+
+func (d *Deduper) addOnDisk(result KeyValue) error {
+	if d.spill == nil {
+		f, err := ioutil.TempFile(d.TempDir, "json2csv-dedupe-")
+		if err != nil {
+			return err
+		}
+		w := bufio.NewWriter(f)
+		d.spill = &dedupeSpill{
+			records: f,
+			w:       w,
+			enc:     gob.NewEncoder(w),
+			digests: d.newRuns(digestEntrySize, compareDigestEntries, encodeDigestEntry, decodeDigestEntry),
+			kept:    d.newRuns(8, compareIndexes, encodeIndex, decodeIndex),
+		}
+	}
+	s := d.spill
+
+	index := s.count
+	s.count++
+	if err := s.enc.Encode(encodeSpilledRow(result)); err != nil {
+		return err
+	}
+	if sum, ok := d.digest(d.h, result); ok {
+		return s.digests.add(digestEntry{sum, index})
+	}
+	return s.kept.add(index)
+}
+
+// eachOnDisk merges the sorted runs of the digests. Equal digests are adjacent
+// in the merged order, so the first or the last index of each digest is kept.
+// Then the results at the kept indexes are read from the temporary file.
+func (d *Deduper) eachOnDisk(fn func(KeyValue) error) error {
+	s := d.spill
+	if s == nil {
+		return nil
+	}
+
+	var prev digestEntry
+	first := true
+	err := s.digests.each(func(item interface{}) error {
+		e := item.(digestEntry)
+		if first || e.digest != prev.digest {
+			if !first && d.Keep == KeepLast {
+				if err := s.kept.add(prev.index); err != nil {
+					return err
+				}
+			}
+			if d.Keep == KeepFirst {
+				if err := s.kept.add(e.index); err != nil {
+					return err
+				}
+			}
+		}
+		prev, first = e, false
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if !first && d.Keep == KeepLast {
+		if err := s.kept.add(prev.index); err != nil {
+			return err
+		}
+	}
+
+	if err := s.w.Flush(); err != nil {
+		return err
+	}
+	if _, err := s.records.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	dec := gob.NewDecoder(bufio.NewReader(s.records))
+	var next uint64
+	return s.kept.each(func(item interface{}) error {
+		index := item.(uint64)
+		var row spilledRow
+		for ; next <= index; next++ {
+			row = nil
+			if err := dec.Decode(&row); err != nil {
+				return err
+			}
+		}
+		result, err := row.decode()
+		if err != nil {
+			return err
+		}
+		return fn(result)
+	})
+}
+
+func (s *dedupeSpill) close() error {
+	err := s.digests.close()
+	if closeErr := s.kept.close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := s.records.Close(); err == nil {
+		err = closeErr
+	}
+	if removeErr := os.Remove(s.records.Name()); err == nil {
+		err = removeErr
+	}
+	return err
+}
+
+// newRuns returns runs of the items encoded in size bytes.
+func (d *Deduper) newRuns(size int, compare func(a, b interface{}) int, encode func(buf []byte, item interface{}), decode func(buf []byte) interface{}) *spilledRuns {
+	return &spilledRuns{
+		size:    d.BufferSize,
+		dir:     d.TempDir,
+		prefix:  "json2csv-dedupe-",
+		compare: compare,
+		encoder: func(w io.Writer) func(interface{}) error {
+			buf := make([]byte, size)
+			return func(item interface{}) error {
+				encode(buf, item)
+				_, err := w.Write(buf)
+				return err
+			}
+		},
+		decoder: func(r io.Reader) func() (interface{}, error) {
+			buf := make([]byte, size)
+			return func() (interface{}, error) {
+				if _, err := io.ReadFull(r, buf); err != nil {
+					return nil, err
+				}
+				return decode(buf), nil
+			}
+		},
+	}
+}
+
+// compareDigestEntries orders the entries by the digests, and then by the
+// indexes.
+func compareDigestEntries(a, b interface{}) int {
+	x, y := a.(digestEntry), b.(digestEntry)
+	if c := bytes.Compare(x.digest[:], y.digest[:]); c != 0 {
+		return c
+	}
+	return compareIndexes(x.index, y.index)
+}
+
+func encodeDigestEntry(buf []byte, item interface{}) {
+	e := item.(digestEntry)
+	copy(buf, e.digest[:])
+	binary.BigEndian.PutUint64(buf[sha256.Size:], e.index)
+}
+
+func decodeDigestEntry(buf []byte) interface{} {
+	var e digestEntry
+	copy(e.digest[:], buf[:sha256.Size])
+	e.index = binary.BigEndian.Uint64(buf[sha256.Size:])
+	return e
+}
+
+func compareIndexes(a, b interface{}) int {
+	x, y := a.(uint64), b.(uint64)
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
+
+func encodeIndex(buf []byte, item interface{}) {
+	binary.BigEndian.PutUint64(buf, item.(uint64))
+}
+
+func decodeIndex(buf []byte) interface{} {
+	return binary.BigEndian.Uint64(buf)
+}
@@ -0,0 +1,75 @@
+package json2csv
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestDedupeResults(t *testing.T) {
+	obj, err := json2obj(`[
+		{"id": 1, "v": "a", "n": 1},
+		{"id": 2, "v": "b"},
+		{"id": 1, "v": "a", "n": 1.0},
+		{"id": 1, "v": "c"},
+		{"id": 3, "v": "b", "n": null},
+		{"id": 4, "v": "b", "n": "1"},
+		{"v": "x"},
+		{"v": "x"},
+		{"id": 2, "v": "b"}
+	]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		keys     []string
+		keep     DedupeKeep
+		expected []int // indexes of kept results
+	}{
+		{nil, KeepFirst, []int{0, 1, 3, 4, 5, 6}},
+		{nil, KeepLast, []int{2, 3, 4, 5, 7, 8}},
+		{[]string{"/id"}, KeepFirst, []int{0, 1, 4, 5, 6, 7}},
+		{[]string{"/id"}, KeepLast, []int{3, 4, 5, 6, 7, 8}},
+		{[]string{"/v"}, KeepFirst, []int{0, 1, 3, 6}},
+		{[]string{"/v", "/n"}, KeepFirst, []int{0, 1, 3, 4, 5, 6}},
+		{[]string{"/missing"}, KeepFirst, []int{0, 1, 2, 3, 4, 5, 6, 7, 8}},
+	}
+
+	for caseIndex, testCase := range testCases {
+		results, err := (&Converter{KeepNulls: true}).Convert(obj)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := []KeyValue{}
+		for _, i := range testCase.expected {
+			expected = append(expected, results[i])
+		}
+
+		actual := DedupeResults(results, testCase.keys, testCase.keep)
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("%d: Expected %v, but %v", caseIndex, expected, actual)
+		}
+
+		for _, bufferSize := range []int{1, 2, 3, 9} {
+			dir, err := ioutil.TempDir("", "json2csv-test-")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+
+			deduper := &Deduper{Keys: testCase.keys, Keep: testCase.keep, BufferSize: bufferSize, TempDir: dir}
+			actual, err := deduper.Dedupe(results)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(actual, expected) {
+				t.Errorf("%d (buffer %d): Expected %v, but %v", caseIndex, bufferSize, expected, actual)
+			}
+			if files, _ := ioutil.ReadDir(dir); len(files) != 0 {
+				t.Errorf("%d (buffer %d): Expected temporary files to be removed, but %d files", caseIndex, bufferSize, len(files))
+			}
+		}
+	}
+}
@@ -0,0 +1,770 @@
+package expr
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/yukithm/json2csv/jsonpointer"
+)
+
+// Values in evaluation are nil, missing, bool, string, *big.Rat, and arrays
+// and objects as they are in the record.
+
+// missingValue is the value of a JSON Pointer which does not exist.
+type missingValue struct{}
+
+var missing = missingValue{}
+
+type node interface {
+	eval(record interface{}) (interface{}, error)
+}
+
+type literalNode struct {
+	value interface{}
+}
+
+func (n *literalNode) eval(record interface{}) (interface{}, error) {
+	return n.value, nil
+}
+
+type pointerNode struct {
+	pointer jsonpointer.JSONPointer
+}
+
+func (n *pointerNode) eval(record interface{}) (interface{}, error) {
+	return lookup(record, n.pointer), nil
+}
+
+type arrayNode struct {
+	elements []node
+}
+
+func (n *arrayNode) eval(record interface{}) (interface{}, error) {
+	array := make([]interface{}, 0, len(n.elements))
+	for _, e := range n.elements {
+		v, err := e.eval(record)
+		if err != nil {
+			return nil, err
+		}
+		if v == missing {
+			v = nil
+		}
+		array = append(array, v)
+	}
+	return array, nil
+}
+
+type notNode struct {
+	operand node
+}
+
+func (n *notNode) eval(record interface{}) (interface{}, error) {
+	v, err := n.operand.eval(record)
+	if err != nil {
+		return nil, err
+	}
+	return !truthy(v), nil
+}
+
+type andNode struct {
+	left, right node
+}
+
+func (n *andNode) eval(record interface{}) (interface{}, error) {
+	v, err := n.left.eval(record)
+	if err != nil || !truthy(v) {
+		return false, err
+	}
+	v, err = n.right.eval(record)
+	if err != nil {
+		return nil, err
+	}
+	return truthy(v), nil
+}
+
+type orNode struct {
+	left, right node
+}
+
+func (n *orNode) eval(record interface{}) (interface{}, error) {
+	v, err := n.left.eval(record)
+	if err != nil {
+		return nil, err
+	}
+	if truthy(v) {
+		return true, nil
+	}
+	v, err = n.right.eval(record)
+	if err != nil {
+		return nil, err
+	}
+	return truthy(v), nil
+}
+
+type comparisonNode struct {
+	op          string
+	left, right node
+	re          *regexp.Regexp // compiled if the pattern is a literal
+}
+
+func (n *comparisonNode) eval(record interface{}) (interface{}, error) {
+	left, err := n.left.eval(record)
+	if err != nil {
+		return nil, err
+	}
+	right, err := n.right.eval(record)
+	if err != nil {
+		return nil, err
+	}
+	left, right = value(left), value(right)
+
+	switch n.op {
+	case "==":
+		return equal(left, right), nil
+	case "!=":
+		return !equal(left, right), nil
+	case "<", "<=", ">", ">=":
+		c, ok := compare(left, right)
+		if !ok {
+			return false, nil
+		}
+		switch n.op {
+		case "<":
+			return c < 0, nil
+		case "<=":
+			return c <= 0, nil
+		case ">":
+			return c > 0, nil
+		default:
+			return c >= 0, nil
+		}
+	case "=~", "!~":
+		matched, err := n.match(left, right)
+		if err != nil {
+			return nil, err
+		}
+		return matched == (n.op == "=~"), nil
+	case "contains":
+		return contains(left, right), nil
+	case "in":
+		return contains(right, left), nil
+	case "startswith", "endswith":
+		s, ok1 := left.(string)
+		affix, ok2 := right.(string)
+		if !ok1 || !ok2 {
+			return false, nil
+		}
+		if n.op == "startswith" {
+			return strings.HasPrefix(s, affix), nil
+		}
+		return strings.HasSuffix(s, affix), nil
+	}
+	return nil, fmt.Errorf("unknown operator %q", n.op)
+}
+
+func (n *comparisonNode) match(left, right interface{}) (bool, error) {
+	re := n.re
+	if re == nil {
+		pattern, ok := right.(string)
+		if !ok {
+			return false, errors.New("regular expression must be a string")
+		}
+		var err error
+		re, err = regexp.Compile(pattern)
+		if err != nil {
+			return false, err
+		}
+	}
+	s, ok := left.(string)
+	return ok && re.MatchString(s), nil
+}
+
+type arithmeticNode struct {
+	op          string
+	left, right node
+}
+
+func (n *arithmeticNode) eval(record interface{}) (interface{}, error) {
+	left, err := n.left.eval(record)
+	if err != nil {
+		return nil, err
+	}
+	right, err := n.right.eval(record)
+	if err != nil {
+		return nil, err
+	}
+	x, ok1 := value(left).(*big.Rat)
+	y, ok2 := value(right).(*big.Rat)
+	if !ok1 || !ok2 {
+		return nil, nil
+	}
+
+	switch n.op {
+	case "+":
+		return new(big.Rat).Add(x, y), nil
+	case "-":
+		return new(big.Rat).Sub(x, y), nil
+	case "*":
+		return new(big.Rat).Mul(x, y), nil
+	case "/":
+		if y.Sign() == 0 {
+			return nil, nil
+		}
+		return new(big.Rat).Quo(x, y), nil
+	case "%":
+		if y.Sign() == 0 {
+			return nil, nil
+		}
+		// x - y * trunc(x / y), which has the sign of x
+		q := new(big.Rat).Quo(x, y)
+		trunc := new(big.Rat).SetInt(new(big.Int).Quo(q.Num(), q.Denom()))
+		return new(big.Rat).Sub(x, trunc.Mul(trunc, y)), nil
+	}
+	return nil, fmt.Errorf("unknown operator %q", n.op)
+}
+
+type callNode struct {
+	name string
+	f    function
+	args []node
+}
+
+func (n *callNode) eval(record interface{}) (interface{}, error) {
+	args := make([]interface{}, 0, len(n.args))
+	for _, arg := range n.args {
+		v, err := arg.eval(record)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, v)
+	}
+	v, err := n.f.call(record, args)
+	if err != nil {
+		return nil, fmt.Errorf("%s(): %s", n.name, err)
+	}
+	return v, nil
+}
+
+// function is a builtin function. maxArgs is negative if it is variadic.
+// Arguments may be missing, and other values are not converted by value().
+type function struct {
+	minArgs, maxArgs int
+	call             func(record interface{}, args []interface{}) (interface{}, error)
+}
+
+var functions map[string]function
+
+func init() {
+	functions = map[string]function{
+		"exists": {1, 1, func(record interface{}, args []interface{}) (interface{}, error) {
+			return args[0] != missing, nil
+		}},
+		"get": {1, 2, func(record interface{}, args []interface{}) (interface{}, error) {
+			// get(pointer) or get(value, pointer)
+			if len(args) == 2 {
+				record, args = args[0], args[1:]
+				if record == missing {
+					return missing, nil
+				}
+			}
+			s, ok := value(args[0]).(string)
+			if !ok {
+				return nil, errors.New("JSON Pointer must be a string")
+			}
+			pointer, err := jsonpointer.New(s)
+			if err != nil {
+				return nil, err
+			}
+			return lookup(record, pointer), nil
+		}},
+		"at": {2, 2, func(record interface{}, args []interface{}) (interface{}, error) {
+			// negative indexes count from the end
+			rv := reflect.ValueOf(value(args[0]))
+			i, ok := intValue(args[1])
+			if !isArray(rv) || !ok {
+				return missing, nil
+			}
+			if i < 0 {
+				i += rv.Len()
+			}
+			if i < 0 || i >= rv.Len() {
+				return missing, nil
+			}
+			return fromJSON(rv.Index(i).Interface()), nil
+		}},
+		"len": {1, 1, func(record interface{}, args []interface{}) (interface{}, error) {
+			switch v := value(args[0]).(type) {
+			case string:
+				return big.NewRat(int64(utf8.RuneCountInString(v)), 1), nil
+			case nil, bool, *big.Rat:
+				return nil, nil
+			default:
+				rv := reflect.ValueOf(v)
+				switch rv.Kind() {
+				case reflect.Slice, reflect.Array, reflect.Map:
+					return big.NewRat(int64(rv.Len()), 1), nil
+				}
+				return nil, nil
+			}
+		}},
+		"lower": stringFunction(strings.ToLower),
+		"upper": stringFunction(strings.ToUpper),
+		"trim":  stringFunction(strings.TrimSpace),
+		"number": {1, 1, func(record interface{}, args []interface{}) (interface{}, error) {
+			switch v := value(args[0]).(type) {
+			case *big.Rat:
+				return v, nil
+			case string:
+				if r := parseNumber(strings.TrimSpace(v)); r != nil {
+					return r, nil
+				}
+			}
+			return nil, nil
+		}},
+		"string": {1, 1, func(record interface{}, args []interface{}) (interface{}, error) {
+			return toString(value(args[0]))
+		}},
+		"concat": {1, -1, func(record interface{}, args []interface{}) (interface{}, error) {
+			var b strings.Builder
+			for _, arg := range args {
+				s, err := toString(value(arg))
+				if err != nil {
+					return nil, err
+				}
+				b.WriteString(s.(string))
+			}
+			return b.String(), nil
+		}},
+		"substr": {2, 3, func(record interface{}, args []interface{}) (interface{}, error) {
+			// substr(s, start[, length]) in runes. Negative start counts
+			// from the end.
+			s, ok := value(args[0]).(string)
+			start, ok2 := intValue(args[1])
+			if !ok || !ok2 {
+				return nil, nil
+			}
+			runes := []rune(s)
+			if start < 0 {
+				start += len(runes)
+			}
+			start = clamp(start, 0, len(runes))
+			end := len(runes)
+			if len(args) == 3 {
+				length, ok := intValue(args[2])
+				if !ok {
+					return nil, nil
+				}
+				end = clamp(start+length, start, len(runes))
+			}
+			return string(runes[start:end]), nil
+		}},
+		"replace": {3, 3, func(record interface{}, args []interface{}) (interface{}, error) {
+			s, ok1 := value(args[0]).(string)
+			old, ok2 := value(args[1]).(string)
+			replacement, ok3 := value(args[2]).(string)
+			if !ok1 || !ok2 || !ok3 {
+				return nil, nil
+			}
+			return strings.Replace(s, old, replacement, -1), nil
+		}},
+		"split": {2, 2, func(record interface{}, args []interface{}) (interface{}, error) {
+			s, ok1 := value(args[0]).(string)
+			sep, ok2 := value(args[1]).(string)
+			if !ok1 || !ok2 {
+				return nil, nil
+			}
+			var array []interface{}
+			for _, e := range strings.Split(s, sep) {
+				array = append(array, e)
+			}
+			return array, nil
+		}},
+		"join": {2, 2, func(record interface{}, args []interface{}) (interface{}, error) {
+			rv := reflect.ValueOf(value(args[0]))
+			sep, ok := value(args[1]).(string)
+			if !isArray(rv) || !ok {
+				return nil, nil
+			}
+			elements := make([]string, 0, rv.Len())
+			for i := 0; i < rv.Len(); i++ {
+				s, err := toString(value(rv.Index(i).Interface()))
+				if err != nil {
+					return nil, err
+				}
+				elements = append(elements, s.(string))
+			}
+			return strings.Join(elements, sep), nil
+		}},
+		"if": {3, 3, func(record interface{}, args []interface{}) (interface{}, error) {
+			if truthy(args[0]) {
+				return args[1], nil
+			}
+			return args[2], nil
+		}},
+		"coalesce": {1, -1, func(record interface{}, args []interface{}) (interface{}, error) {
+			for _, arg := range args {
+				if value(arg) != nil {
+					return arg, nil
+				}
+			}
+			return nil, nil
+		}},
+		"abs": {1, 1, func(record interface{}, args []interface{}) (interface{}, error) {
+			if r, ok := value(args[0]).(*big.Rat); ok {
+				return new(big.Rat).Abs(r), nil
+			}
+			return nil, nil
+		}},
+		"round": {1, 2, func(record interface{}, args []interface{}) (interface{}, error) {
+			// round(x[, digits]) rounds half away from zero.
+			r, ok := value(args[0]).(*big.Rat)
+			if !ok {
+				return nil, nil
+			}
+			digits := 0
+			if len(args) == 2 {
+				if digits, ok = intValue(args[1]); !ok || digits < 0 {
+					return nil, nil
+				}
+			}
+			return roundRat(r, digits), nil
+		}},
+	}
+}
+
+// stringFunction returns a function which converts a string. It returns
+// null for values other than strings.
+func stringFunction(f func(string) string) function {
+	return function{1, 1, func(record interface{}, args []interface{}) (interface{}, error) {
+		if s, ok := value(args[0]).(string); ok {
+			return f(s), nil
+		}
+		return nil, nil
+	}}
+}
+
+// intValue returns the value as int if it is an integer.
+func intValue(v interface{}) (int, bool) {
+	r, ok := value(v).(*big.Rat)
+	if !ok || !r.IsInt() || !r.Num().IsInt64() {
+		return 0, false
+	}
+	i := r.Num().Int64()
+	if int64(int(i)) != i {
+		return 0, false
+	}
+	return int(i), true
+}
+
+func clamp(i, min, max int) int {
+	if i < min {
+		return min
+	}
+	if i > max {
+		return max
+	}
+	return i
+}
+
+// roundRat rounds the number to the digits after the point, half away from
+// zero.
+func roundRat(r *big.Rat, digits int) *big.Rat {
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(digits)), nil)
+	scaled := new(big.Rat).Mul(r, new(big.Rat).SetInt(scale))
+	q, m := new(big.Int).QuoRem(scaled.Num(), scaled.Denom(), new(big.Int))
+	// |m| * 2 >= denom
+	if new(big.Int).Lsh(new(big.Int).Abs(m), 1).Cmp(scaled.Denom()) >= 0 {
+		q.Add(q, big.NewInt(int64(scaled.Sign())))
+	}
+	return new(big.Rat).SetFrac(q, scale)
+}
+
+// lookup returns the value at the pointer in the record, or missing.
+func lookup(record interface{}, pointer jsonpointer.JSONPointer) interface{} {
+	v := record
+	for _, token := range pointer {
+		rv := reflect.ValueOf(v)
+		switch rv.Kind() {
+		case reflect.Map:
+			if rv.Type().Key().Kind() != reflect.String {
+				return missing
+			}
+			e := rv.MapIndex(reflect.ValueOf(string(token)).Convert(rv.Type().Key()))
+			if !e.IsValid() {
+				return missing
+			}
+			v = e.Interface()
+		case reflect.Slice, reflect.Array:
+			if !token.IsIndex() {
+				return missing
+			}
+			i, err := strconv.Atoi(string(token))
+			if err != nil || i >= rv.Len() {
+				return missing
+			}
+			v = rv.Index(i).Interface()
+		default:
+			return missing
+		}
+	}
+	return fromJSON(v)
+}
+
+// fromJSON converts numbers into *big.Rat. Other values are returned as
+// they are.
+func fromJSON(v interface{}) interface{} {
+	switch n := v.(type) {
+	case json.Number:
+		if r := parseNumber(n.String()); r != nil {
+			return r
+		}
+		return n.String()
+	case float64:
+		return floatRat(n)
+	case float32:
+		return floatRat(float64(n))
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return new(big.Rat).SetInt64(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return new(big.Rat).SetInt(new(big.Int).SetUint64(rv.Uint()))
+	}
+	return v
+}
+
+func floatRat(f float64) interface{} {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil
+	}
+	return new(big.Rat).SetFloat64(f)
+}
+
+var numberPattern = regexp.MustCompile(`^-?(?:[0-9]+\.?[0-9]*|\.[0-9]+)(?:[eE][+-]?[0-9]+)?$`)
+
+// parseNumber parses a decimal number. It returns nil if s is not a number.
+func parseNumber(s string) *big.Rat {
+	if !numberPattern.MatchString(s) {
+		return nil
+	}
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return nil
+	}
+	return r
+}
+
+// value converts missing into null, and numbers into *big.Rat.
+func value(v interface{}) interface{} {
+	if v == missing {
+		return nil
+	}
+	return fromJSON(v)
+}
+
+func truthy(v interface{}) bool {
+	switch v := value(v).(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case string:
+		return v != ""
+	case *big.Rat:
+		return v.Sign() != 0
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return rv.Len() > 0
+	}
+	return true
+}
+
+// equal compares values. Numbers are compared by their values, and arrays
+// and objects are compared deeply.
+func equal(a, b interface{}) bool {
+	a, b = value(a), value(b)
+	switch x := a.(type) {
+	case nil:
+		return b == nil
+	case *big.Rat:
+		y, ok := b.(*big.Rat)
+		return ok && x.Cmp(y) == 0
+	case string, bool:
+		return a == b
+	}
+
+	ra, rb := reflect.ValueOf(a), reflect.ValueOf(b)
+	switch {
+	case isArray(ra) && isArray(rb):
+		if ra.Len() != rb.Len() {
+			return false
+		}
+		for i := 0; i < ra.Len(); i++ {
+			if !equal(ra.Index(i).Interface(), rb.Index(i).Interface()) {
+				return false
+			}
+		}
+		return true
+	case ra.Kind() == reflect.Map && rb.Kind() == reflect.Map:
+		if ra.Len() != rb.Len() {
+			return false
+		}
+		for _, key := range ra.MapKeys() {
+			e := rb.MapIndex(key)
+			if !e.IsValid() || !equal(ra.MapIndex(key).Interface(), e.Interface()) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// compare compares numbers or strings. It returns false if they cannot be
+// ordered.
+func compare(a, b interface{}) (int, bool) {
+	switch x := a.(type) {
+	case *big.Rat:
+		if y, ok := b.(*big.Rat); ok {
+			return x.Cmp(y), true
+		}
+	case string:
+		if y, ok := b.(string); ok {
+			return strings.Compare(x, y), true
+		}
+	}
+	return 0, false
+}
+
+// contains reports whether the string contains the substring, the array
+// contains the element, or the object contains the key.
+func contains(container, v interface{}) bool {
+	if s, ok := container.(string); ok {
+		sub, ok := v.(string)
+		return ok && strings.Contains(s, sub)
+	}
+
+	rv := reflect.ValueOf(container)
+	switch {
+	case isArray(rv):
+		for i := 0; i < rv.Len(); i++ {
+			if equal(rv.Index(i).Interface(), v) {
+				return true
+			}
+		}
+	case rv.Kind() == reflect.Map && rv.Type().Key().Kind() == reflect.String:
+		if key, ok := v.(string); ok {
+			return rv.MapIndex(reflect.ValueOf(key).Convert(rv.Type().Key())).IsValid()
+		}
+	}
+	return false
+}
+
+func isArray(rv reflect.Value) bool {
+	return rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array
+}
+
+// toJSON converts numbers into json.Number, and missing into null.
+func toJSON(v interface{}) interface{} {
+	switch v := v.(type) {
+	case missingValue:
+		return nil
+	case *big.Rat:
+		return json.Number(FormatDecimal(v))
+	case []interface{}:
+		array := make([]interface{}, 0, len(v))
+		for _, e := range v {
+			array = append(array, toJSON(e))
+		}
+		return array
+	case map[string]interface{}:
+		obj := make(map[string]interface{}, len(v))
+		for key, e := range v {
+			obj[key] = toJSON(e)
+		}
+		return obj
+	}
+	return v
+}
+
+// toString converts the value into a string. Numbers are written in decimal
+// notation, null is "", and arrays and objects are written in JSON.
+func toString(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case *big.Rat:
+		return FormatDecimal(v), nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// FormatDecimal formats the number in decimal notation. Numbers which cannot
+// be written exactly (e.g. 1/3) are rounded to 16 digits after the point.
+func FormatDecimal(r *big.Rat) string {
+	if r.IsInt() {
+		return r.Num().String()
+	}
+	digits, exact := decimalDigits(r.Denom())
+	if !exact {
+		digits = 16
+	}
+	s := r.FloatString(digits)
+	if !exact {
+		s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+		if s == "-0" {
+			s = "0"
+		}
+	}
+	return s
+}
+
+// decimalDigits returns the number of digits after the point to write
+// 1/denom exactly, and false if it is a recurring decimal.
+func decimalDigits(denom *big.Int) (int, bool) {
+	d := new(big.Int).Set(denom)
+	two, five := big.NewInt(2), big.NewInt(5)
+	m := new(big.Int)
+	twos, fives := 0, 0
+	for {
+		q, r := new(big.Int).QuoRem(d, two, m)
+		if r.Sign() != 0 {
+			break
+		}
+		d, twos = q, twos+1
+	}
+	for {
+		q, r := new(big.Int).QuoRem(d, five, m)
+		if r.Sign() != 0 {
+			break
+		}
+		d, fives = q, fives+1
+	}
+	if d.Cmp(big.NewInt(1)) != 0 {
+		return 0, false
+	}
+	if twos > fives {
+		return twos, true
+	}
+	return fives, true
+}
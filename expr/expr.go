@@ -0,0 +1,95 @@
+// Package expr implements a small expression language over JSON Pointers.
+//
+// An expression refers to values in a record with JSON Pointers, e.g.
+//
+//	/status == 200 && /user/age > 30 && /tags contains "vip"
+//
+// Operators are (in order of increasing precedence):
+//
+//	||
+//	&&
+//	== != < <= > >= =~ !~ contains startswith endswith in
+//	+ -
+//	* / %
+//	! - (unary)
+//
+// Numbers are exact decimals, so json.Number values are never rounded into
+// float64. Arithmetic on values other than numbers and division by zero
+// result in null. Strings are compared by bytes. Values of different types
+// are never equal nor ordered, and a missing value is null (use exists() to
+// tell them apart).
+//
+// A JSON Pointer lasts until a space or one of ()[],!=<>&|"'+*% so operators
+// should be separated by spaces. Keys which contain them can be written with
+// the escapes of JSON Pointer and get(), e.g. get("/first name").
+package expr
+
+import (
+	"fmt"
+)
+
+// Expr is a parsed expression.
+type Expr struct {
+	src  string
+	root node
+}
+
+// SyntaxError is returned when the expression cannot be parsed.
+type SyntaxError struct {
+	Expr   string
+	Offset int
+	Msg    string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%s at offset %d in %q", e.Msg, e.Offset, e.Expr)
+}
+
+// Parse parses the expression.
+func Parse(src string) (*Expr, error) {
+	tokens, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{src: src, tokens: tokens}
+	root, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+	return &Expr{src, root}, nil
+}
+
+// MustParse is like Parse but panics if the expression cannot be parsed.
+func MustParse(src string) *Expr {
+	e, err := Parse(src)
+	if err != nil {
+		panic(err)
+	}
+	return e
+}
+
+// String returns the source of the expression.
+func (e *Expr) String() string {
+	return e.src
+}
+
+// Eval evaluates the expression against the record. Numbers are returned as
+// json.Number, and missing values as nil.
+func (e *Expr) Eval(record interface{}) (interface{}, error) {
+	v, err := e.root.eval(record)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", e.src, err)
+	}
+	return toJSON(v), nil
+}
+
+// Match evaluates the expression against the record and reports whether the
+// result is truthy. false, null, missing values, 0, "" and empty arrays and
+// objects are falsy.
+func (e *Expr) Match(record interface{}) (bool, error) {
+	v, err := e.root.eval(record)
+	if err != nil {
+		return false, fmt.Errorf("%s: %s", e.src, err)
+	}
+	return truthy(v), nil
+}
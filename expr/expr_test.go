@@ -0,0 +1,286 @@
+package expr
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"testing"
+)
+
+func decode(t *testing.T, s string) interface{} {
+	d := json.NewDecoder(bytes.NewReader([]byte(s)))
+	d.UseNumber()
+	var v interface{}
+	if err := d.Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+var testRecord = `{
+	"status": 200,
+	"code": "200",
+	"price": 19.990,
+	"big": 12345678901234567890.5,
+	"user": {"name": "Alice", "age": 31, "email": "alice@example.com", "first name": "A"},
+	"tags": ["vip", "new"],
+	"scores": [1, 2.0, 3],
+	"deleted": null,
+	"active": true,
+	"empty": "",
+	"a/b": 1,
+	"items": [{"id": 1}, {"id": 2}]
+}`
+
+var testMatchCases = []struct {
+	expr     string
+	expected bool
+}{
+	{`/status == 200`, true},
+	{`/status == 200.0`, true},
+	{`/status == 2e2`, true},
+	{`/status != 200`, false},
+	{`/code == 200`, false},
+	{`/code == "200"`, true},
+	{`number(/code) == 200`, true},
+	{`/price == 19.99`, true},
+	{`/price > 19.98 && /price < 19.991`, true},
+	{`/big > 12345678901234567890`, true},
+	{`/big == 12345678901234567890`, false},
+	{`/status == 200 && /user/age > 30 && /tags contains "vip"`, true},
+	{`/user/age >= 31 && /user/age <= 31`, true},
+	{`/user/name < "Bob"`, true},
+	{`/user/name > 1`, false},
+	{`/user/name <= 1`, false},
+	{`/tags contains "old"`, false},
+	{`/scores contains 2`, true},
+	{`/user contains "email"`, true},
+	{`/user/email contains "@example"`, true},
+	{`/user/email endswith ".com" && /user/email startswith "alice"`, true},
+	{`/status in [200, 201]`, true},
+	{`/status in [404]`, false},
+	{`"vi" in /tags/0`, true},
+	{`/user/email =~ "^[a-z]+@"`, true},
+	{`/user/email !~ "^[a-z]+@"`, false},
+	{`/user/name =~ "^a"`, false},
+	{`/status =~ "200"`, false},
+	{`lower(/user/name) == "alice"`, true},
+	{`upper(/user/name) == "ALICE"`, true},
+	{`len(/tags) == 2 && len(/user/name) == 5`, true},
+	{`exists(/user/email)`, true},
+	{`exists(/user/phone)`, false},
+	{`!exists(/user/phone)`, true},
+	{`exists(/deleted)`, true},
+	{`exists(/tags/1) && !exists(/tags/2)`, true},
+	{`exists(/status/0)`, false},
+	{`exists(/a~1b)`, true},
+	{`exists(get("/user/first name"))`, true},
+	{`/deleted == null`, true},
+	{`/user/phone == null`, true},
+	{`/user/phone != "x"`, true},
+	{`/user/phone > 0`, false},
+	{`/items/1/id == 2`, true},
+	{`/scores == [1, 2, 3]`, true},
+	{`/active`, true},
+	{`/empty`, false},
+	{`/deleted`, false},
+	{`!/active || /status == 200`, true},
+	{`(/status == 404 || /status == 200) && !(/active == false)`, true},
+	{`string(/price) == "19.99"`, true},
+	{`string(/tags) == '["vip","new"]'`, true},
+	{`trim(" a ") == "a"`, true},
+}
+
+func TestMatch(t *testing.T) {
+	record := decode(t, testRecord)
+	for _, testCase := range testMatchCases {
+		e, err := Parse(testCase.expr)
+		if err != nil {
+			t.Errorf("%s: %s", testCase.expr, err)
+			continue
+		}
+		actual, err := e.Match(record)
+		if err != nil {
+			t.Errorf("%s: %s", testCase.expr, err)
+			continue
+		}
+		if actual != testCase.expected {
+			t.Errorf("%s: Expected %v, but %v", testCase.expr, testCase.expected, actual)
+		}
+	}
+}
+
+func TestMatchGoValues(t *testing.T) {
+	record := map[string]interface{}{
+		"n":    int64(3),
+		"f":    0.5,
+		"tags": []string{"a", "b"},
+	}
+	e := MustParse(`/n == 3 && /f == 0.5 && /tags contains "b"`)
+	if ok, err := e.Match(record); err != nil || !ok {
+		t.Errorf("Expected true, but %v (%v)", ok, err)
+	}
+}
+
+func TestMatchError(t *testing.T) {
+	record := decode(t, `{"pattern": "(", "n": 1}`)
+	testCases := []string{
+		`"a" =~ /pattern`,
+		`"a" =~ /n`,
+		`exists(get(1))`,
+	}
+	for _, src := range testCases {
+		e, err := Parse(src)
+		if err != nil {
+			t.Errorf("%s: %s", src, err)
+			continue
+		}
+		if _, err := e.Match(record); err == nil {
+			t.Errorf("%s: Expected error", src)
+		}
+	}
+}
+
+func TestParseError(t *testing.T) {
+	testCases := []struct {
+		expr   string
+		offset int
+	}{
+		{``, 0},
+		{`/a ==`, 5},
+		{`/a == 1 == 2`, 8},
+		{`/a && (/b`, 9},
+		{`/a = 1`, 3},
+		{`"abc`, 0},
+		{`/a == 1x`, 6},
+		{`foo(/a)`, 0},
+		{`exists(/a, /b)`, 0},
+		{`/a =~ "("`, 3},
+		{`/a =~ 1`, 3},
+		{`bar`, 0},
+		{`[1, 2`, 5},
+	}
+	for _, testCase := range testCases {
+		_, err := Parse(testCase.expr)
+		if err == nil {
+			t.Errorf("%s: Expected error", testCase.expr)
+			continue
+		}
+		e, ok := err.(*SyntaxError)
+		if !ok {
+			t.Errorf("%s: Expected *SyntaxError, but %T", testCase.expr, err)
+			continue
+		}
+		if e.Offset != testCase.offset {
+			t.Errorf("%s: Expected offset %d, but %d (%s)", testCase.expr, testCase.offset, e.Offset, e)
+		}
+	}
+}
+
+var testEvalRecord = `{
+	"price": 19.99,
+	"qty": 3,
+	"rate": 0.1,
+	"big": 12345678901234567890,
+	"first": "Ada",
+	"last": "Lovelace",
+	"code": "A-001",
+	"tags": ["x", "y", "z"],
+	"items": [{"name": "pen", "price": 1.5}, {"name": "ink", "price": 2.25}],
+	"note": null,
+	"x-id": 7
+}`
+
+var testEvalCases = []struct {
+	expr     string
+	expected interface{}
+}{
+	{`/price * /qty`, json.Number("59.97")},
+	{`/price * /qty * (1 + /rate)`, json.Number("65.967")},
+	{`0.1 + 0.2`, json.Number("0.3")},
+	{`/big + 1`, json.Number("12345678901234567891")},
+	{`/qty - 5`, json.Number("-2")},
+	{`-/qty`, json.Number("-3")},
+	{`/x-id - 1`, json.Number("6")},
+	{`/qty*2`, json.Number("6")},
+	{`1 + 2 * 3`, json.Number("7")},
+	{`(1 + 2) * 3`, json.Number("9")},
+	{`10 / 4`, json.Number("2.5")},
+	{`1 / 3`, json.Number("0.3333333333333333")},
+	{`2 / 3`, json.Number("0.6666666666666667")},
+	{`1 / 0`, nil},
+	{`7 % 3`, json.Number("1")},
+	{`-7 % 3`, json.Number("-1")},
+	{`5.5 % 2`, json.Number("1.5")},
+	{`/first + 1`, nil},
+	{`/missing * 2`, nil},
+	{`round(/price * /qty * 1.08)`, json.Number("65")},
+	{`round(2.345, 2)`, json.Number("2.35")},
+	{`round(-2.5)`, json.Number("-3")},
+	{`abs(-1.5)`, json.Number("1.5")},
+	{`concat(/first, " ", /last)`, "Ada Lovelace"},
+	{`concat(/code, "-", /qty, "-", /note, /missing)`, "A-001-3-"},
+	{`concat(/price)`, "19.99"},
+	{`substr(/last, 0, 4)`, "Love"},
+	{`substr(/last, -4)`, "lace"},
+	{`substr(/last, 6, 100)`, "ce"},
+	{`replace(/code, "-", "")`, "A001"},
+	{`split(/code, "-")`, []interface{}{"A", "001"}},
+	{`join(/tags, "|")`, "x|y|z"},
+	{`upper(at(split(/code, "-"), 0))`, "A"},
+	{`if(/qty > 2, "bulk", "single")`, "bulk"},
+	{`if(/note, 1, 2)`, json.Number("2")},
+	{`coalesce(/note, /missing, "n/a")`, "n/a"},
+	{`at(/tags, 1)`, "y"},
+	{`at(/tags, -1)`, "z"},
+	{`at(/tags, 3)`, nil},
+	{`/items/1/name`, "ink"},
+	{`get(at(/items, -1), "/name")`, "ink"},
+	{`get(/items/0, "/price") + get(/items/1, "/price")`, json.Number("3.75")},
+	{`len(/items)`, json.Number("2")},
+	{`[1, /qty]`, []interface{}{json.Number("1"), json.Number("3")}},
+	{`/qty > 2`, true},
+	{`/missing`, nil},
+}
+
+func TestEval(t *testing.T) {
+	record := decode(t, testEvalRecord)
+	for _, testCase := range testEvalCases {
+		e, err := Parse(testCase.expr)
+		if err != nil {
+			t.Errorf("%s: %s", testCase.expr, err)
+			continue
+		}
+		actual, err := e.Eval(record)
+		if err != nil {
+			t.Errorf("%s: %s", testCase.expr, err)
+			continue
+		}
+		if !reflect.DeepEqual(actual, testCase.expected) {
+			t.Errorf("%s: Expected %#v, but %#v", testCase.expr, testCase.expected, actual)
+		}
+	}
+}
+
+func TestFormatDecimal(t *testing.T) {
+	testCases := []struct {
+		n, d     int64
+		expected string
+	}{
+		{3, 1, "3"},
+		{-3, 1, "-3"},
+		{1, 4, "0.25"},
+		{1, 1024, "0.0009765625"},
+		{1, 3, "0.3333333333333333"},
+		{-2, 3, "-0.6666666666666667"},
+		{1, 30000000000000000, "0"},
+		{-1, 30000000000000000, "0"},
+	}
+	for _, testCase := range testCases {
+		actual := FormatDecimal(big.NewRat(testCase.n, testCase.d))
+		if actual != testCase.expected {
+			t.Errorf("%d/%d: Expected %s, but %s", testCase.n, testCase.d, testCase.expected, actual)
+		}
+	}
+}
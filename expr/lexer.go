@@ -0,0 +1,215 @@
+package expr
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type tokenKind uint
+
+const (
+	eofToken tokenKind = iota
+	numberToken
+	stringToken
+	pointerToken
+	identToken
+	operatorToken
+)
+
+type token struct {
+	kind tokenKind
+	text string // decoded text of strings, otherwise the source text
+	pos  int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case eofToken:
+		return "end of expression"
+	case stringToken:
+		return fmt.Sprintf("string %q", t.text)
+	default:
+		return fmt.Sprintf("%q", t.text)
+	}
+}
+
+// operators sorted by length so that the longest one matches first
+var operators = []string{
+	"==", "!=", "<=", ">=", "=~", "!~", "&&", "||",
+	"<", ">", "!", "(", ")", "[", "]", ",",
+	"+", "-", "*", "/", "%",
+}
+
+// pointerDelimiters terminate JSON Pointers in addition to spaces. '-' and
+// '/' are valid in JSON Pointers, so subtraction and division need spaces.
+const pointerDelimiters = "()[],!=<>&|\"'+*%"
+
+type lexer struct {
+	src    string
+	pos    int
+	tokens []token
+}
+
+func tokenize(src string) ([]token, error) {
+	l := &lexer{src: src}
+	for {
+		t, err := l.next()
+		if err != nil {
+			return nil, err
+		}
+		l.tokens = append(l.tokens, t)
+		if t.kind == eofToken {
+			return l.tokens, nil
+		}
+	}
+}
+
+func (l *lexer) errorf(pos int, format string, args ...interface{}) error {
+	return &SyntaxError{l.src, pos, fmt.Sprintf(format, args...)}
+}
+
+// operandExpected reports whether the next token is an operand, where '/'
+// starts a JSON Pointer.
+func (l *lexer) operandExpected() bool {
+	if len(l.tokens) == 0 {
+		return true
+	}
+	last := l.tokens[len(l.tokens)-1]
+	switch last.kind {
+	case operatorToken:
+		return last.text != ")" && last.text != "]"
+	case identToken:
+		return isWordOperator(last.text)
+	default:
+		return false
+	}
+}
+
+func (l *lexer) next() (token, error) {
+	for l.pos < len(l.src) {
+		r, size := utf8.DecodeRuneInString(l.src[l.pos:])
+		if !unicode.IsSpace(r) {
+			break
+		}
+		l.pos += size
+	}
+	if l.pos >= len(l.src) {
+		return token{eofToken, "", l.pos}, nil
+	}
+
+	start := l.pos
+	c := l.src[l.pos]
+	switch {
+	case c == '/' && l.operandExpected():
+		return l.scanPointer(), nil
+	case c == '"' || c == '\'':
+		return l.scanString(c)
+	case c >= '0' && c <= '9' || c == '.':
+		return l.scanNumber()
+	case c == '_' || isLetter(c):
+		for l.pos < len(l.src) && (l.src[l.pos] == '_' || isLetter(l.src[l.pos]) || isDigit(l.src[l.pos])) {
+			l.pos++
+		}
+		return token{identToken, l.src[start:l.pos], start}, nil
+	}
+
+	for _, op := range operators {
+		if strings.HasPrefix(l.src[l.pos:], op) {
+			l.pos += len(op)
+			return token{operatorToken, op, start}, nil
+		}
+	}
+	r, _ := utf8.DecodeRuneInString(l.src[l.pos:])
+	return token{}, l.errorf(start, "unexpected character %q", r)
+}
+
+func (l *lexer) scanPointer() token {
+	start := l.pos
+	for l.pos < len(l.src) {
+		r, size := utf8.DecodeRuneInString(l.src[l.pos:])
+		if unicode.IsSpace(r) || strings.ContainsRune(pointerDelimiters, r) {
+			break
+		}
+		l.pos += size
+	}
+	return token{pointerToken, l.src[start:l.pos], start}
+}
+
+func (l *lexer) scanString(quote byte) (token, error) {
+	start := l.pos
+	l.pos++
+	var b strings.Builder
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case c == quote:
+			l.pos++
+			return token{stringToken, b.String(), start}, nil
+		case c == '\\':
+			if l.pos+1 >= len(l.src) {
+				return token{}, l.errorf(l.pos, "unterminated string")
+			}
+			switch e := l.src[l.pos+1]; e {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			case '\\', '"', '\'', '/':
+				b.WriteByte(e)
+			default:
+				return token{}, l.errorf(l.pos, "invalid escape \\%c", e)
+			}
+			l.pos += 2
+		default:
+			b.WriteByte(c)
+			l.pos++
+		}
+	}
+	return token{}, l.errorf(start, "unterminated string")
+}
+
+func (l *lexer) scanNumber() (token, error) {
+	start := l.pos
+	digits := func() int {
+		n := 0
+		for l.pos < len(l.src) && isDigit(l.src[l.pos]) {
+			l.pos++
+			n++
+		}
+		return n
+	}
+
+	n := digits()
+	if l.pos < len(l.src) && l.src[l.pos] == '.' {
+		l.pos++
+		n += digits()
+	}
+	if n == 0 {
+		return token{}, l.errorf(start, "invalid number")
+	}
+	if l.pos < len(l.src) && (l.src[l.pos] == 'e' || l.src[l.pos] == 'E') {
+		l.pos++
+		if l.pos < len(l.src) && (l.src[l.pos] == '+' || l.src[l.pos] == '-') {
+			l.pos++
+		}
+		if digits() == 0 {
+			return token{}, l.errorf(start, "invalid number")
+		}
+	}
+	if l.pos < len(l.src) && (l.src[l.pos] == '_' || isLetter(l.src[l.pos])) {
+		return token{}, l.errorf(start, "invalid number")
+	}
+	return token{numberToken, l.src[start:l.pos], start}, nil
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
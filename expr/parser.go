@@ -0,0 +1,279 @@
+package expr
+
+import (
+	"math/big"
+	"regexp"
+
+	"github.com/yukithm/json2csv/jsonpointer"
+)
+
+// comparison operators including words such as "contains"
+var comparisonOperators = map[string]bool{
+	"==": true, "!=": true, "<": true, "<=": true, ">": true, ">=": true,
+	"=~": true, "!~": true,
+	"contains": true, "startswith": true, "endswith": true, "in": true,
+}
+
+func isWordOperator(s string) bool {
+	return comparisonOperators[s] && isLetter(s[0])
+}
+
+type parser struct {
+	src    string
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) advance() token {
+	t := p.tokens[p.pos]
+	if t.kind != eofToken {
+		p.pos++
+	}
+	return t
+}
+
+// accept consumes the operator if it is the next token.
+func (p *parser) accept(op string) bool {
+	t := p.peek()
+	if (t.kind == operatorToken || t.kind == identToken) && t.text == op {
+		p.advance()
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(op string) error {
+	if !p.accept(op) {
+		return p.unexpected(p.peek())
+	}
+	return nil
+}
+
+func (p *parser) errorf(pos int, msg string) error {
+	return &SyntaxError{p.src, pos, msg}
+}
+
+func (p *parser) unexpected(t token) error {
+	return p.errorf(t.pos, "unexpected "+t.String())
+}
+
+func (p *parser) parse() (node, error) {
+	n, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != eofToken {
+		return nil, p.unexpected(t)
+	}
+	return n, nil
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseComparison()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("&&") {
+		right, err := p.parseComparison()
+		if err != nil {
+			return nil, err
+		}
+		left = &andNode{left, right}
+	}
+	return left, nil
+}
+
+// parseComparison parses a comparison. Comparisons cannot be chained.
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	t := p.peek()
+	if (t.kind != operatorToken && t.kind != identToken) || !comparisonOperators[t.text] {
+		return left, nil
+	}
+	p.advance()
+	right, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+
+	n := &comparisonNode{op: t.text, left: left, right: right}
+	if t.text == "=~" || t.text == "!~" {
+		if lit, ok := right.(*literalNode); ok {
+			s, ok := lit.value.(string)
+			if !ok {
+				return nil, p.errorf(t.pos, "regular expression must be a string")
+			}
+			n.re, err = regexp.Compile(s)
+			if err != nil {
+				return nil, p.errorf(t.pos, err.Error())
+			}
+		}
+	}
+	return n, nil
+}
+
+func (p *parser) parseAdditive() (node, error) {
+	return p.parseArithmetic(p.parseMultiplicative, "+", "-")
+}
+
+func (p *parser) parseMultiplicative() (node, error) {
+	return p.parseArithmetic(p.parseUnary, "*", "/", "%")
+}
+
+// parseArithmetic parses left-associative binary operators.
+func (p *parser) parseArithmetic(operand func() (node, error), ops ...string) (node, error) {
+	left, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		if t.kind != operatorToken || !containsString(ops, t.text) {
+			return left, nil
+		}
+		p.advance()
+		right, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		left = &arithmeticNode{t.text, left, right}
+	}
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.accept("!") {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{operand}, nil
+	}
+	if p.accept("-") {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &arithmeticNode{"-", &literalNode{new(big.Rat)}, operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	t := p.advance()
+	switch t.kind {
+	case numberToken:
+		r, ok := new(big.Rat).SetString(t.text)
+		if !ok {
+			return nil, p.errorf(t.pos, "invalid number")
+		}
+		return &literalNode{r}, nil
+	case stringToken:
+		return &literalNode{t.text}, nil
+	case pointerToken:
+		pointer, err := jsonpointer.New(t.text)
+		if err != nil {
+			return nil, p.errorf(t.pos, err.Error())
+		}
+		return &pointerNode{pointer}, nil
+	case identToken:
+		switch t.text {
+		case "true":
+			return &literalNode{true}, nil
+		case "false":
+			return &literalNode{false}, nil
+		case "null":
+			return &literalNode{nil}, nil
+		}
+		if p.peek().text == "(" {
+			return p.parseCall(t)
+		}
+		return nil, p.errorf(t.pos, "unknown identifier "+t.String())
+	case operatorToken:
+		switch t.text {
+		case "(":
+			n, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return n, nil
+		case "[":
+			elements, err := p.parseList("]")
+			if err != nil {
+				return nil, err
+			}
+			return &arrayNode{elements}, nil
+		}
+	}
+	return nil, p.unexpected(t)
+}
+
+func (p *parser) parseCall(name token) (node, error) {
+	f, ok := functions[name.text]
+	if !ok {
+		return nil, p.errorf(name.pos, "unknown function "+name.String())
+	}
+	p.advance() // "("
+	args, err := p.parseList(")")
+	if err != nil {
+		return nil, err
+	}
+	if len(args) < f.minArgs || (f.maxArgs >= 0 && len(args) > f.maxArgs) {
+		return nil, p.errorf(name.pos, "wrong number of arguments to "+name.text)
+	}
+	return &callNode{name.text, f, args}, nil
+}
+
+// parseList parses expressions separated by commas until the end operator.
+func (p *parser) parseList(end string) ([]node, error) {
+	var list []node
+	if p.accept(end) {
+		return list, nil
+	}
+	for {
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, n)
+		if p.accept(end) {
+			return list, nil
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func containsString(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,159 @@
+package json2csv
+
+import (
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// ExternalSorter sorts results in the same order as SortResults, holding at
+// most RunSize rows in memory for sorting. Larger inputs are sorted in runs
+// of RunSize rows, which are written to temporary files and merged.
+//
+// Add the results with Add, read them in order with Each, and remove the
+// temporary files with Close.
+type ExternalSorter struct {
+	Keys    []SortKey
+	Missing MissingOrder
+
+	// RunSize is the number of rows sorted in memory at once.
+	// Zero or negative means no limit.
+	RunSize int
+
+	// TempDir is the directory of the temporary files. The default directory
+	// for temporary files is used if it is empty.
+	TempDir string
+
+	runs *spilledRuns
+}
+
+// NewExternalSorter returns new ExternalSorter.
+func NewExternalSorter(keys []SortKey, missing MissingOrder, runSize int) *ExternalSorter {
+	return &ExternalSorter{
+		Keys:    keys,
+		Missing: missing,
+		RunSize: runSize,
+	}
+}
+
+// Add adds the result. It writes the rows held in memory to a temporary file
+// when they reach RunSize.
+func (s *ExternalSorter) Add(result KeyValue) error {
+	if s.runs == nil {
+		s.runs = &spilledRuns{
+			size:   s.RunSize,
+			dir:    s.TempDir,
+			prefix: "json2csv-sort-",
+			compare: func(a, b interface{}) int {
+				return compareSortValues(a.(sortRow).values, b.(sortRow).values, s.Keys, s.Missing)
+			},
+			encoder: func(w io.Writer) func(interface{}) error {
+				enc := gob.NewEncoder(w)
+				return func(item interface{}) error {
+					return enc.Encode(encodeSpilledRow(item.(sortRow).result))
+				}
+			},
+			decoder: func(r io.Reader) func() (interface{}, error) {
+				dec := gob.NewDecoder(r)
+				return func() (interface{}, error) {
+					var row spilledRow
+					if err := dec.Decode(&row); err != nil {
+						return nil, err
+					}
+					result, err := row.decode()
+					if err != nil {
+						return nil, err
+					}
+					return newSortRow(result, s.Keys), nil
+				}
+			},
+		}
+	}
+	return s.runs.add(newSortRow(result, s.Keys))
+}
+
+// Each calls fn for each result in the sorted order, merging the temporary
+// files and the rows held in memory. It can be called only once.
+func (s *ExternalSorter) Each(fn func(KeyValue) error) error {
+	if s.runs == nil {
+		return nil
+	}
+	return s.runs.each(func(item interface{}) error {
+		return fn(item.(sortRow).result)
+	})
+}
+
+// Close removes the temporary files.
+func (s *ExternalSorter) Close() error {
+	if s.runs == nil {
+		return nil
+	}
+	return s.runs.close()
+}
+
+// spilledRow is a result written to a temporary file. Values keep their
+// types, so that the rows read back are written in the same way.
+type spilledRow map[string]spilledValue
+
+type spilledValue struct {
+	Kind byte // 'z': null, 'b': bool, 's': string, 'n': json.Number, 'i': int64, 'u': uint64, 'f': float64
+	Text string
+}
+
+func encodeSpilledRow(result KeyValue) spilledRow {
+	row := make(spilledRow, len(result))
+	for key, value := range result {
+		var v spilledValue
+		switch value := value.(type) {
+		case nil:
+			v = spilledValue{'z', ""}
+		case bool:
+			v = spilledValue{'b', strconv.FormatBool(value)}
+		case json.Number:
+			v = spilledValue{'n', string(value)}
+		case int64:
+			v = spilledValue{'i', strconv.FormatInt(value, 10)}
+		case uint64:
+			v = spilledValue{'u', strconv.FormatUint(value, 10)}
+		case float64:
+			v = spilledValue{'f', strconv.FormatFloat(value, 'g', -1, 64)}
+		default:
+			v = spilledValue{'s', toString(value)}
+		}
+		row[key] = v
+	}
+	return row
+}
+
+func (row spilledRow) decode() (KeyValue, error) {
+	result := make(KeyValue, len(row))
+	for key, v := range row {
+		var value interface{}
+		var err error
+		switch v.Kind {
+		case 'z':
+			value = nil
+		case 'b':
+			value, err = strconv.ParseBool(v.Text)
+		case 'n':
+			value = json.Number(v.Text)
+		case 'i':
+			value, err = strconv.ParseInt(v.Text, 10, 64)
+		case 'u':
+			value, err = strconv.ParseUint(v.Text, 10, 64)
+		case 'f':
+			value, err = strconv.ParseFloat(v.Text, 64)
+		case 's':
+			value = v.Text
+		default:
+			err = fmt.Errorf("unknown value kind %q", v.Kind)
+		}
+		if err != nil {
+			return nil, err
+		}
+		result[key] = value
+	}
+	return result, nil
+}
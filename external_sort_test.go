@@ -0,0 +1,97 @@
+package json2csv
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestExternalSorter(t *testing.T) {
+	obj, err := json2obj(`[
+		{"id": 1, "last": "Smith", "created": 20},
+		{"id": 2, "last": "Doe", "created": 3},
+		{"id": 3, "created": 100},
+		{"id": 4, "last": "Smith", "created": 100.5},
+		{"id": 5, "last": null, "created": 1e2},
+		{"id": 6, "last": "Doe", "created": 3.0},
+		{"id": 7, "last": true},
+		{"id": 8, "last": 10}
+	]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := []SortKey{{"/last", false}, {"/created", true}}
+
+	expected, err := (&Converter{KeepNulls: true}).Convert(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	SortResults(expected, keys, MissingLast)
+
+	for _, runSize := range []int{0, 1, 2, 3, 8, 9} {
+		dir, err := ioutil.TempDir("", "json2csv-test-")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+
+		results, err := (&Converter{KeepNulls: true}).Convert(obj)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sorter := NewExternalSorter(keys, MissingLast, runSize)
+		sorter.TempDir = dir
+		for _, result := range results {
+			if err := sorter.Add(result); err != nil {
+				t.Fatal(err)
+			}
+		}
+		var actual []KeyValue
+		err = sorter.Each(func(result KeyValue) error {
+			actual = append(actual, result)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("%d: Expected %v, but %v", runSize, expected, actual)
+		}
+
+		if err := sorter.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if files, _ := ioutil.ReadDir(dir); len(files) != 0 {
+			t.Errorf("%d: Expected temporary files to be removed, but %d files", runSize, len(files))
+		}
+	}
+}
+
+func TestExternalSorterValueTypes(t *testing.T) {
+	results := []KeyValue{
+		{"/k": json.Number("2"), "/n": nil, "/b": true, "/s": "x", "/i": int64(-1), "/u": uint64(18446744073709551615), "/f": 0.1},
+		{"/k": json.Number("1")},
+	}
+	sorter := NewExternalSorter([]SortKey{{"/k", false}}, MissingLast, 1)
+	defer sorter.Close()
+	for _, result := range results {
+		if err := sorter.Add(result); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var actual []KeyValue
+	err := sorter.Each(func(result KeyValue) error {
+		actual = append(actual, result)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []KeyValue{results[1], results[0]}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected %v, but %v", expected, actual)
+	}
+}
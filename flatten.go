@@ -1,11 +1,15 @@
 package json2csv
 
 import (
+	"encoding"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"math/big"
 	"reflect"
 	"sort"
 	"strconv"
+	"time"
 
 	"github.com/yukithm/json2csv/jsonpointer"
 )
@@ -16,7 +20,16 @@ type mapKeys []reflect.Value
 
 func (k mapKeys) Len() int           { return len(k) }
 func (k mapKeys) Swap(i, j int)      { k[i], k[j] = k[j], k[i] }
-func (k mapKeys) Less(i, j int) bool { return k[i].String() < k[j].String() }
+func (k mapKeys) Less(i, j int) bool { return mapKeyString(k[i]) < mapKeyString(k[j]) }
+
+// mapKeyString returns the string form of the map key.
+// Maps decoded from binary formats such as CBOR may have non-string keys.
+func mapKeyString(key reflect.Value) string {
+	if key.Kind() == reflect.String {
+		return key.String()
+	}
+	return fmt.Sprint(key.Interface())
+}
 
 func sortedMapKeys(v reflect.Value) []reflect.Value {
 	var keys mapKeys = v.MapKeys()
@@ -36,16 +49,18 @@ func (kv KeyValue) Keys() []string {
 	return keys
 }
 
-func flatten(obj interface{}) (KeyValue, error) {
+func flatten(obj interface{}, keepNulls bool) (KeyValue, error) {
 	f := make(KeyValue, 0)
 	key := jsonpointer.JSONPointer{}
-	if err := _flatten(f, obj, key); err != nil {
+	if err := _flatten(f, obj, key, keepNulls); err != nil {
 		return nil, err
 	}
 	return f, nil
 }
 
-func _flatten(out KeyValue, obj interface{}, key jsonpointer.JSONPointer) error {
+// _flatten flattens obj into out under key. Nulls are omitted like missing
+// values unless keepNulls is true.
+func _flatten(out KeyValue, obj interface{}, key jsonpointer.JSONPointer, keepNulls bool) error {
 	value, ok := obj.(reflect.Value)
 	if !ok {
 		value = reflect.ValueOf(obj)
@@ -54,19 +69,21 @@ func _flatten(out KeyValue, obj interface{}, key jsonpointer.JSONPointer) error
 		value = value.Elem()
 	}
 
-	if value.IsValid() {
-		vt := value.Type()
-		if vt.AssignableTo(jsonNumberType) {
-			out[key.String()] = value.Interface().(json.Number)
-			return nil
-		}
+	if s, ok := scalarOf(value); ok {
+		out[key.String()] = s
+		return nil
 	}
 
 	switch value.Kind() {
+	case reflect.Invalid:
+		// null
+		if keepNulls {
+			out[key.String()] = nil
+		}
 	case reflect.Map:
-		_flattenMap(out, value, key)
+		_flattenMap(out, value, key, keepNulls)
 	case reflect.Slice:
-		_flattenSlice(out, value, key)
+		_flattenSlice(out, value, key, keepNulls)
 	case reflect.String:
 		out[key.String()] = value.String()
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
@@ -83,19 +100,59 @@ func _flatten(out KeyValue, obj interface{}, key jsonpointer.JSONPointer) error
 	return nil
 }
 
-func _flattenMap(out map[string]interface{}, value reflect.Value, prefix jsonpointer.JSONPointer) {
+func _flattenMap(out map[string]interface{}, value reflect.Value, prefix jsonpointer.JSONPointer, keepNulls bool) {
 	keys := sortedMapKeys(value)
 	for _, key := range keys {
 		pointer := prefix.Clone()
-		pointer.AppendString(key.String())
-		_flatten(out, value.MapIndex(key).Interface(), pointer)
+		pointer.AppendString(mapKeyString(key))
+		_flatten(out, value.MapIndex(key).Interface(), pointer, keepNulls)
 	}
 }
 
-func _flattenSlice(out map[string]interface{}, value reflect.Value, prefix jsonpointer.JSONPointer) {
+func _flattenSlice(out map[string]interface{}, value reflect.Value, prefix jsonpointer.JSONPointer, keepNulls bool) {
 	for i := 0; i < value.Len(); i++ {
 		pointer := prefix.Clone()
 		pointer.AppendString(strconv.Itoa(i))
-		_flatten(out, value.Index(i).Interface(), pointer)
+		_flatten(out, value.Index(i).Interface(), pointer, keepNulls)
+	}
+}
+
+// scalarOf returns the cell value of json.Number and the types handled by
+// scalarValue, which are not expanded even if they are slices or structs.
+func scalarOf(value reflect.Value) (interface{}, bool) {
+	if !value.IsValid() {
+		return nil, false
+	}
+	if value.Type().AssignableTo(jsonNumberType) {
+		return value.Interface().(json.Number), true
+	}
+	if value.CanInterface() {
+		return scalarValue(value.Interface())
+	}
+	return nil, false
+}
+
+// scalarValue returns the cell value of types decoded from binary formats
+// such as MessagePack, CBOR and BSON, which have no JSON counterpart.
+// Binary values are encoded in base64, times in RFC 3339 and big integers in
+// decimal. Other types which implement encoding.TextMarshaler (e.g. BSON
+// ObjectID) are converted into their text.
+func scalarValue(v interface{}) (interface{}, bool) {
+	switch v := v.(type) {
+	case []byte:
+		return base64.StdEncoding.EncodeToString(v), true
+	case time.Time:
+		return v.Format(time.RFC3339Nano), true
+	case *big.Int:
+		return json.Number(v.String()), true
+	case big.Int:
+		return json.Number(v.String()), true
+	case encoding.TextMarshaler:
+		text, err := v.MarshalText()
+		if err != nil {
+			return nil, false
+		}
+		return string(text), true
 	}
+	return nil, false
 }
@@ -3,33 +3,78 @@ package json2csv
 
 import (
 	"errors"
+	"fmt"
 	"reflect"
+	"strings"
+
+	"github.com/yukithm/json2csv/jsonpointer"
 )
 
+// Filter reports whether the record should be converted. The record is an
+// element of the array of objects, or the whole data otherwise, before it
+// is flattened.
+type Filter func(record interface{}) (bool, error)
+
+// ComputedColumn is a column whose value is computed from each record.
+type ComputedColumn struct {
+	// Key is the JSON Pointer of the column. (e.g. "/total")
+	Key string
+
+	// Compute returns the value of the record before it is flattened.
+	// Arrays and objects are flattened under Key.
+	Compute func(record interface{}) (interface{}, error)
+}
+
+// Converter converts JSON to CSV with hooks on each record.
+type Converter struct {
+	// Filter skips records for which it returns false. If nil, all
+	// records are converted.
+	Filter Filter
+
+	// Computed adds the columns to each record after it is flattened. They
+	// replace the same keys and the keys under them, and are ordered and
+	// styled like other keys.
+	Computed []ComputedColumn
+
+	// KeepNulls keeps null values as nil in the results. By default they
+	// are omitted like missing values, so columns with only nulls are not
+	// written.
+	KeepNulls bool
+}
+
 // JSON2CSV converts JSON to CSV.
 func JSON2CSV(data interface{}) ([]KeyValue, error) {
+	return (&Converter{}).Convert(data)
+}
+
+// Convert converts JSON to CSV.
+func (c *Converter) Convert(data interface{}) ([]KeyValue, error) {
 	results := []KeyValue{}
 	v := valueOf(data)
 	switch v.Kind() {
 	case reflect.Map:
 		if v.Len() > 0 {
-			result, err := flatten(v)
+			result, err := c.convert(v)
 			if err != nil {
 				return nil, err
 			}
-			results = append(results, result)
+			if result != nil {
+				results = append(results, result)
+			}
 		}
 	case reflect.Slice:
 		if isObjectArray(v) {
 			for i := 0; i < v.Len(); i++ {
-				result, err := flatten(v.Index(i))
+				result, err := c.convert(v.Index(i))
 				if err != nil {
 					return nil, err
 				}
-				results = append(results, result)
+				if result != nil {
+					results = append(results, result)
+				}
 			}
 		} else if v.Len() > 0 {
-			result, err := flatten(v)
+			result, err := c.convert(v)
 			if err != nil {
 				return nil, err
 			}
@@ -44,6 +89,44 @@ func JSON2CSV(data interface{}) ([]KeyValue, error) {
 	return results, nil
 }
 
+// convert flattens the record. It returns nil if the record is skipped.
+func (c *Converter) convert(record reflect.Value) (KeyValue, error) {
+	if c.Filter != nil {
+		ok, err := c.Filter(valueOf(record).Interface())
+		if err != nil || !ok {
+			return nil, err
+		}
+	}
+	result, err := flatten(record, c.KeepNulls)
+	if err != nil {
+		return nil, err
+	}
+	for _, column := range c.Computed {
+		key, err := jsonpointer.New(column.Key)
+		if err != nil {
+			return nil, err
+		}
+		v, err := column.Compute(valueOf(record).Interface())
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", column.Key, err)
+		}
+		deleteKeys(result, key.String())
+		if err := _flatten(result, v, key, c.KeepNulls); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// deleteKeys deletes the key and the keys under it.
+func deleteKeys(result KeyValue, key string) {
+	for k := range result {
+		if k == key || strings.HasPrefix(k, key+"/") {
+			delete(result, k)
+		}
+	}
+}
+
 func isObjectArray(obj interface{}) bool {
 	value := valueOf(obj)
 	if value.Kind() != reflect.Slice {
@@ -3,8 +3,11 @@ package json2csv
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
+	"math/big"
 	"reflect"
 	"testing"
+	"time"
 )
 
 // Decode JSON with UseNumber option.
@@ -112,6 +115,11 @@ var testJSON2CSVCases = []struct {
 		[]KeyValue{{"/float_value": json.Number("146163870.300")}},
 		``,
 	},
+	{
+		`{"id": 1, "value": null, "list": [null, 2]}`,
+		[]KeyValue{{"/id": json.Number("1"), "/list/1": json.Number("2")}},
+		``,
+	},
 	{`"foo"`, nil, `Unsupported JSON structure.`},
 	{`123`, nil, `Unsupported JSON structure.`},
 	{`true`, nil, `Unsupported JSON structure.`},
@@ -134,3 +142,115 @@ func TestJSON2CSV(t *testing.T) {
 		}
 	}
 }
+
+func TestJSON2CSVBinaryTypes(t *testing.T) {
+	obj := map[string]interface{}{
+		"bin":  []byte{0xde, 0xad, 0xbe, 0xef},
+		"time": time.Date(2024, 1, 2, 3, 4, 5, 6000000, time.UTC),
+		"big":  new(big.Int).Lsh(big.NewInt(1), 70),
+		"map":  map[interface{}]interface{}{uint64(1): "a", "b": int8(2)},
+	}
+	expected := []KeyValue{{
+		"/bin":   "3q2+7w==",
+		"/time":  "2024-01-02T03:04:05.006Z",
+		"/big":   json.Number("1180591620717411303424"),
+		"/map/1": "a",
+		"/map/b": int64(2),
+	}}
+
+	actual, err := JSON2CSV(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Expected %#v, but %#v", expected, actual)
+	}
+}
+
+func TestConverterKeepNulls(t *testing.T) {
+	obj, err := json2obj(`[{"a": null, "b": 1, "c": [null, 2]}]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &Converter{KeepNulls: true}
+	expected := []KeyValue{
+		{"/a": nil, "/b": json.Number("1"), "/c/0": nil, "/c/1": json.Number("2")},
+	}
+
+	actual, err := c.Convert(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Expected %#v, but %#v", expected, actual)
+	}
+}
+
+func TestConverterFilter(t *testing.T) {
+	obj, err := json2obj(`[{"id": 1, "ok": true}, {"id": 2, "ok": false}, {"id": 3, "ok": true}]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &Converter{
+		Filter: func(record interface{}) (bool, error) {
+			return record.(map[string]interface{})["ok"] == true, nil
+		},
+	}
+	expected := []KeyValue{
+		{"/id": json.Number("1"), "/ok": true},
+		{"/id": json.Number("3"), "/ok": true},
+	}
+
+	actual, err := c.Convert(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Expected %#v, but %#v", expected, actual)
+	}
+
+	c.Filter = func(record interface{}) (bool, error) {
+		return false, errors.New("filter error")
+	}
+	if _, err := c.Convert(obj); err == nil || err.Error() != "filter error" {
+		t.Errorf("Expected filter error, but %v", err)
+	}
+}
+
+func TestConverterComputed(t *testing.T) {
+	obj, err := json2obj(`[{"id": 1, "tags": ["a"], "user": {"name": "a", "age": 3}, "note": "x"}, {"id": 2, "user": "b"}]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &Converter{
+		Computed: []ComputedColumn{
+			{"/label", func(record interface{}) (interface{}, error) {
+				return "id-" + string(record.(map[string]interface{})["id"].(json.Number)), nil
+			}},
+			{"/id", func(record interface{}) (interface{}, error) {
+				return json.Number("0"), nil
+			}},
+			{"/pair", func(record interface{}) (interface{}, error) {
+				return []interface{}{"x", nil}, nil
+			}},
+			{"/user", func(record interface{}) (interface{}, error) {
+				return map[string]interface{}{"name": "c"}, nil
+			}},
+			{"/note", func(record interface{}) (interface{}, error) {
+				return nil, nil
+			}},
+		},
+	}
+	expected := []KeyValue{
+		{"/id": json.Number("0"), "/tags/0": "a", "/label": "id-1", "/pair/0": "x", "/user/name": "c"},
+		{"/id": json.Number("0"), "/label": "id-2", "/pair/0": "x", "/user/name": "c"},
+	}
+
+	actual, err := c.Convert(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Expected %#v, but %#v", expected, actual)
+	}
+}
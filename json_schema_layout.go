@@ -0,0 +1,232 @@
+package json2csv
+
+import (
+	"encoding/json"
+	"math/big"
+	"strconv"
+	"time"
+
+	"github.com/yukithm/json2csv/jsonpointer"
+	"github.com/yukithm/json2csv/jsonschema"
+)
+
+// JSONSchemaLayout returns keys of the columns in the order of properties in
+// the schema, and cell formatters derived from "type" and "format".
+// The formatters write numbers and values not matching the format with
+// formatter, or StandardValueFormatter if formatter is nil.
+//
+// Arrays are expanded up to maxItems. Arrays without maxItems, objects
+// without properties, schemas without types (e.g. true and {}) and recursive
+// schemas are expanded by the keys present in the results.
+func JSONSchemaLayout(schema *jsonschema.Schema, results []KeyValue, formatter ValueFormatter) ([]string, map[string]CellFormatter, error) {
+	observed, err := sortedPointers(results)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	l := &schemaLayout{
+		observed:   observed,
+		seen:       map[string]bool{},
+		path:       map[*jsonschema.Schema]bool{},
+		formatters: map[string]CellFormatter{},
+		formatter:  formatter,
+	}
+	if err := l.walk(schema, jsonpointer.JSONPointer{}); err != nil {
+		return nil, nil, err
+	}
+	return l.columns, l.formatters, nil
+}
+
+type schemaLayout struct {
+	observed   pointers
+	columns    []string
+	seen       map[string]bool
+	path       map[*jsonschema.Schema]bool
+	formatters map[string]CellFormatter
+	formatter  ValueFormatter
+}
+
+func (l *schemaLayout) walk(schema *jsonschema.Schema, key jsonpointer.JSONPointer) error {
+	s, err := schema.Resolve()
+	if err != nil {
+		return err
+	}
+	if s == nil || l.path[s] {
+		l.addObserved(key)
+		return nil
+	}
+	if s.Boolean != nil {
+		if *s.Boolean {
+			l.addObserved(key)
+		}
+		return nil
+	}
+
+	l.path[s] = true
+	defer delete(l.path, s)
+
+	props, err := properties(s)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case len(props) > 0:
+		for _, prop := range props {
+			pointer := key.Clone()
+			pointer.AppendString(prop.Name)
+			if err := l.walk(prop.Schema, pointer); err != nil {
+				return err
+			}
+		}
+	case s.Items != nil || len(s.PrefixItems) > 0 || s.Type.Has("array"):
+		n := l.observedItems(key)
+		if s.MaxItems != nil {
+			n = *s.MaxItems
+		}
+		for i := 0; i < n; i++ {
+			item := s.Items
+			if i < len(s.PrefixItems) {
+				item = s.PrefixItems[i]
+			}
+			pointer := key.Clone()
+			pointer.AppendString(strconv.Itoa(i))
+			if err := l.walk(item, pointer); err != nil {
+				return err
+			}
+		}
+	case s.Type.Has("object") || untyped(s):
+		l.addObserved(key)
+	default:
+		l.add(key.String())
+		if format := schemaFormatter(s, l.formatter); format != nil {
+			l.formatters[key.String()] = format
+		}
+	}
+	return nil
+}
+
+// untyped reports whether the schema accepts any type of values like true,
+// e.g. {} or a schema with only annotations.
+func untyped(s *jsonschema.Schema) bool {
+	return len(s.Type) == 0 && s.Format == "" && s.Enum == nil && s.Const == nil &&
+		len(s.AllOf) == 0 && len(s.AnyOf) == 0 && len(s.OneOf) == 0
+}
+
+// properties returns properties of the schema including those in allOf.
+func properties(s *jsonschema.Schema) (jsonschema.Properties, error) {
+	props := append(jsonschema.Properties{}, s.Properties...)
+	for _, sub := range s.AllOf {
+		sub, err := sub.Resolve()
+		if err != nil {
+			return nil, err
+		}
+		if sub == nil || sub.Boolean != nil {
+			continue
+		}
+		subProps, err := properties(sub)
+		if err != nil {
+			return nil, err
+		}
+		for _, prop := range subProps {
+			if s.Property(prop.Name) == nil {
+				props = append(props, prop)
+			}
+		}
+	}
+	return props, nil
+}
+
+func (l *schemaLayout) add(key string) {
+	if !l.seen[key] {
+		l.seen[key] = true
+		l.columns = append(l.columns, key)
+	}
+}
+
+// addObserved adds keys in the results under the prefix.
+func (l *schemaLayout) addObserved(prefix jsonpointer.JSONPointer) {
+	for _, p := range l.observed {
+		if hasPrefix(p, prefix) {
+			l.add(p.String())
+		}
+	}
+}
+
+// observedItems returns the number of array items in the results under the prefix.
+func (l *schemaLayout) observedItems(prefix jsonpointer.JSONPointer) int {
+	n := 0
+	for _, p := range l.observed {
+		if len(p) > len(prefix) && hasPrefix(p, prefix) && p[len(prefix)].IsIndex() {
+			if i, _ := strconv.Atoi(string(p[len(prefix)])); i+1 > n {
+				n = i + 1
+			}
+		}
+	}
+	return n
+}
+
+func hasPrefix(p, prefix jsonpointer.JSONPointer) bool {
+	if len(p) < len(prefix) {
+		return false
+	}
+	for i := range prefix {
+		if p[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func schemaFormatter(s *jsonschema.Schema, formatter ValueFormatter) CellFormatter {
+	if formatter == nil {
+		formatter = NewStandardValueFormatter()
+	}
+	format := formatter.FormatValue
+
+	switch s.Format {
+	case "date":
+		return timeFormatter("2006-01-02", format)
+	case "time":
+		return timeFormatter("15:04:05Z07:00", format)
+	}
+
+	switch {
+	case s.Type.Has("integer") && !s.Type.Has("string"):
+		return integerFormatter(format)
+	case s.Type.Has("number") && !s.Type.Has("string"):
+		// StandardValueFormatter writes numbers without exponents.
+		return format
+	}
+	return nil
+}
+
+// timeFormatter returns a formatter that converts RFC 3339 date-time strings to the layout.
+func timeFormatter(layout string, format CellFormatter) CellFormatter {
+	return func(value interface{}) string {
+		if s, ok := value.(string); ok {
+			if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+				return t.Format(layout)
+			}
+		}
+		return format(value)
+	}
+}
+
+// integerFormatter returns a formatter that writes integral numbers without
+// fractions and exponents. (e.g. "1.0" and "1e3" to "1" and "1000")
+func integerFormatter(format CellFormatter) CellFormatter {
+	return func(value interface{}) string {
+		switch v := value.(type) {
+		case json.Number:
+			if r, ok := new(big.Rat).SetString(v.String()); ok && r.IsInt() {
+				return format(json.Number(r.Num().String()))
+			}
+		case float64:
+			if r := new(big.Rat); r.SetFloat64(v) != nil && r.IsInt() {
+				return format(json.Number(r.Num().String()))
+			}
+		}
+		return format(value)
+	}
+}
@@ -0,0 +1,128 @@
+package json2csv
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/yukithm/json2csv/jsonschema"
+)
+
+var testJSONSchemaLayoutSchema = `{
+	"type": "object",
+	"properties": {
+		"name": {"type": "string"},
+		"id": {"type": "integer"},
+		"born": {"type": "string", "format": "date"},
+		"tags": {"type": "array", "items": {"type": "string"}, "maxItems": 2},
+		"scores": {"type": "array", "items": {"type": "number"}},
+		"address": {"$ref": "#/$defs/address"},
+		"extra": {"type": "object"},
+		"meta": {},
+		"parent": {"$ref": "#"}
+	},
+	"$defs": {
+		"address": {
+			"type": "object",
+			"properties": {"zip": {"type": "string"}, "city": {"type": "string"}}
+		}
+	}
+}`
+
+func TestJSONSchemaLayout(t *testing.T) {
+	schema, err := jsonschema.Parse([]byte(testJSONSchemaLayoutSchema))
+	if err != nil {
+		t.Fatal(err)
+	}
+	obj, err := json2obj(`[
+		{"id": 1.0, "born": "2001-02-03T04:05:06Z", "tags": ["a", "b", "c"], "scores": [1, 2, 3]},
+		{"id": 1e3, "extra": {"b": 1, "a": 2}, "meta": {"x": [1]}, "parent": {"id": 1, "name": "foo"}}
+	]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := JSON2CSV(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	columns, formatters, err := JSONSchemaLayout(schema, results, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"/name",
+		"/id",
+		"/born",
+		"/tags/0",
+		"/tags/1",
+		"/scores/0",
+		"/scores/1",
+		"/scores/2",
+		"/address/zip",
+		"/address/city",
+		"/extra/a",
+		"/extra/b",
+		"/meta/x/0",
+		"/parent/id",
+		"/parent/name",
+	}
+	if !reflect.DeepEqual(columns, expected) {
+		t.Errorf("Expected %v, but %v", expected, columns)
+	}
+
+	testFormatterCases := []struct {
+		key      string
+		value    interface{}
+		expected string
+	}{
+		{"/id", json.Number("1.0"), "1"},
+		{"/id", json.Number("1e3"), "1000"},
+		{"/id", json.Number("1.5"), "1.5"},
+		{"/born", "2001-02-03T04:05:06Z", "2001-02-03"},
+		{"/born", "unknown", "unknown"},
+		{"/scores/0", 1000000.0, "1000000"},
+	}
+	for caseIndex, testCase := range testFormatterCases {
+		format, ok := formatters[testCase.key]
+		if !ok {
+			t.Errorf("%d: Expected formatter for %v", caseIndex, testCase.key)
+			continue
+		}
+		if actual := format(testCase.value); actual != testCase.expected {
+			t.Errorf("%d: Expected %v, but %v", caseIndex, testCase.expected, actual)
+		}
+	}
+	if _, ok := formatters["/name"]; ok {
+		t.Errorf("Expected no formatter for /name")
+	}
+}
+
+func TestJSONSchemaLayoutValueFormatter(t *testing.T) {
+	schema, err := jsonschema.Parse([]byte(`{"properties": {"id": {"type": "integer"}, "score": {"type": "number"}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	formatter := &StandardValueFormatter{FloatPrecision: 2, DecimalSeparator: ","}
+	_, formatters, err := JSONSchemaLayout(schema, nil, formatter)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testFormatterCases := []struct {
+		key      string
+		value    interface{}
+		expected string
+	}{
+		{"/id", json.Number("1e3"), "1000"},
+		{"/id", json.Number("1.5"), "1,50"},
+		{"/score", json.Number("1e-1"), "0,10"},
+		{"/score", 1000000.0, "1000000,00"},
+	}
+	for caseIndex, testCase := range testFormatterCases {
+		if actual := formatters[testCase.key](testCase.value); actual != testCase.expected {
+			t.Errorf("%d: Expected %v, but %v", caseIndex, testCase.expected, actual)
+		}
+	}
+}
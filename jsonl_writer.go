@@ -0,0 +1,115 @@
+package json2csv
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// JSONLWriter writes each record as a single-level JSON object per line
+// (JSON Lines). Keys are the headers in HeaderStyle, and missing values are
+// omitted.
+//
+// By default, values keep their types: numbers (including json.Number) and
+// booleans are not quoted. If Stringify is true, all values except null are
+// written as strings formatted by ValueFormatter, in the same form as CSV
+// cells.
+type JSONLWriter struct {
+	w              io.Writer
+	HeaderStyle    KeyStyle
+	Stringify      bool
+	ValueFormatter ValueFormatter
+}
+
+// NewJSONLWriter returns new JSONLWriter with JSONPointerStyle and
+// StandardValueFormatter.
+func NewJSONLWriter(w io.Writer) *JSONLWriter {
+	return &JSONLWriter{
+		w,
+		JSONPointerStyle,
+		false,
+		NewStandardValueFormatter(),
+	}
+}
+
+// WriteJSONL writes JSON Lines.
+func (w *JSONLWriter) WriteJSONL(results []KeyValue) error {
+	pts, err := sortedPointers(results)
+	if err != nil {
+		return err
+	}
+	keys := pts.Strings()
+	names := uniqueKeyNames(headerOf(pts, w.HeaderStyle))
+
+	bw := bufio.NewWriter(w.w)
+	line := &bytes.Buffer{}
+	enc := json.NewEncoder(line)
+	enc.SetEscapeHTML(false)
+	for _, result := range results {
+		line.Reset()
+		line.WriteByte('{')
+		first := true
+		for i, key := range keys {
+			value, ok := result[key]
+			if !ok {
+				continue
+			}
+			if !first {
+				line.WriteByte(',')
+			}
+			first = false
+
+			if err := enc.Encode(names[i]); err != nil {
+				return err
+			}
+			line.Truncate(line.Len() - 1) // trim the newline written by Encode
+			line.WriteByte(':')
+			if err := enc.Encode(w.jsonValue(value)); err != nil {
+				return fmt.Errorf("%s: %s", key, err)
+			}
+			line.Truncate(line.Len() - 1)
+		}
+		line.WriteString("}\n")
+		if _, err := bw.Write(line.Bytes()); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+func (w *JSONLWriter) jsonValue(value interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+	if w.Stringify {
+		if w.ValueFormatter != nil {
+			return w.ValueFormatter.FormatValue(value)
+		}
+		return toString(value)
+	}
+	return value
+}
+
+// uniqueKeyNames makes names unique by appending a sequential number,
+// skipping numbered names which are already in names.
+// Unlike uniqueColumnNames, names are compared case-sensitively.
+func uniqueKeyNames(names []string) []string {
+	reserved := make(map[string]bool, len(names))
+	for _, name := range names {
+		reserved[name] = true
+	}
+
+	used := make(map[string]bool, len(names))
+	unique := make([]string, 0, len(names))
+	for _, name := range names {
+		candidate := name
+		for n := 2; used[candidate] || (candidate != name && reserved[candidate]); n++ {
+			candidate = fmt.Sprintf("%s_%d", name, n)
+		}
+		used[candidate] = true
+		unique = append(unique, candidate)
+	}
+	return unique
+}
@@ -0,0 +1,69 @@
+package json2csv
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+var testJSONLResults = []KeyValue{
+	{"/id": json.Number("1"), "/name": "a<b", "/user/active": true, "/score": json.Number("1.50"), "/tags/0": "x"},
+	{"/id": json.Number("2"), "/name": nil, "/user/active": false, "/big": uint64(18446744073709551615)},
+}
+
+func TestWriteJSONL(t *testing.T) {
+	b := &bytes.Buffer{}
+	w := NewJSONLWriter(b)
+	w.HeaderStyle = DotBracketStyle
+	if err := w.WriteJSONL(testJSONLResults); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"id":1,"name":"a<b","score":1.50,"tags[0]":"x","user.active":true}
+{"big":18446744073709551615,"id":2,"name":null,"user.active":false}
+`
+	if b.String() != expected {
+		t.Errorf("Expected %v, but %v", expected, b.String())
+	}
+}
+
+func TestWriteJSONLStringify(t *testing.T) {
+	b := &bytes.Buffer{}
+	w := NewJSONLWriter(b)
+	w.Stringify = true
+	if err := w.WriteJSONL(testJSONLResults); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"/id":"1","/name":"a<b","/score":"1.50","/tags/0":"x","/user/active":"true"}
+{"/big":"18446744073709551615","/id":"2","/name":null,"/user/active":"false"}
+`
+	if b.String() != expected {
+		t.Errorf("Expected %v, but %v", expected, b.String())
+	}
+}
+
+func TestWriteJSONLStringifyFormatter(t *testing.T) {
+	b := &bytes.Buffer{}
+	w := NewJSONLWriter(b)
+	w.Stringify = true
+	w.ValueFormatter = &StandardValueFormatter{TrueLiteral: "1", FalseLiteral: "0", FloatPrecision: 1, DecimalSeparator: ","}
+	if err := w.WriteJSONL(testJSONLResults[:1]); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"/id":"1","/name":"a<b","/score":"1,5","/tags/0":"x","/user/active":"1"}
+`
+	if b.String() != expected {
+		t.Errorf("Expected %v, but %v", expected, b.String())
+	}
+}
+
+func TestUniqueKeyNames(t *testing.T) {
+	actual := uniqueKeyNames([]string{"a.b", "A.B", "a.b", "a.b_2"})
+	expected := []string{"a.b", "A.B", "a.b_3", "a.b_2"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected %v, but %v", expected, actual)
+	}
+}
@@ -0,0 +1,382 @@
+// Package jsonschema implements a representation of JSON Schema.
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/yukithm/json2csv/jsonpointer"
+)
+
+// Schema is a JSON Schema.
+// Unlike a map, Properties keeps the order in the schema document.
+type Schema struct {
+	// Boolean is set if the schema is true or false.
+	Boolean *bool
+
+	Ref         string
+	Defs        map[string]*Schema
+	Type        Types
+	Format      string
+	Properties  Properties
+	Items       *Schema
+	PrefixItems []*Schema
+	MaxItems    *int
+	AllOf       []*Schema
+
+	// Validation keywords
+	Enum                 []interface{}
+	Const                *Value
+	Required             []string
+	AdditionalProperties *Schema
+	PatternProperties    map[string]*Schema
+	MinProperties        *int
+	MaxProperties        *int
+	MinItems             *int
+	UniqueItems          bool
+	Contains             *Schema
+	Minimum              *json.Number
+	Maximum              *json.Number
+	ExclusiveMinimum     *json.Number
+	ExclusiveMaximum     *json.Number
+	MultipleOf           *json.Number
+	MinLength            *int
+	MaxLength            *int
+	Pattern              string
+	AnyOf                []*Schema
+	OneOf                []*Schema
+	Not                  *Schema
+	If                   *Schema
+	Then                 *Schema
+	Else                 *Schema
+
+	root     *Schema
+	regexp   *regexp.Regexp
+	patterns []patternProperty // sorted by pattern
+}
+
+type patternProperty struct {
+	pattern string
+	re      *regexp.Regexp
+}
+
+// Value holds a JSON value of "const", which may be null.
+type Value struct {
+	Value interface{}
+}
+
+// Property is a pair of the property name and its schema.
+type Property struct {
+	Name   string
+	Schema *Schema
+}
+
+// Properties is an ordered list of Property.
+type Properties []Property
+
+// Types is a list of type names. "type" can be a string or an array.
+type Types []string
+
+// Parse parses a JSON Schema document.
+func Parse(data []byte) (*Schema, error) {
+	var s Schema
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	s.setRoot(&s)
+	return &s, nil
+}
+
+// Read reads and parses a JSON Schema document.
+func Read(r io.Reader) (*Schema, error) {
+	var buf bytes.Buffer
+	if _, err := buf.ReadFrom(r); err != nil {
+		return nil, err
+	}
+	return Parse(buf.Bytes())
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (s *Schema) UnmarshalJSON(data []byte) error {
+	var b bool
+	if err := json.Unmarshal(data, &b); err == nil {
+		s.Boolean = &b
+		return nil
+	}
+
+	var doc struct {
+		Ref         string             `json:"$ref"`
+		Defs        map[string]*Schema `json:"$defs"`
+		Definitions map[string]*Schema `json:"definitions"`
+		Type        Types              `json:"type"`
+		Format      string             `json:"format"`
+		Properties  Properties         `json:"properties"`
+		Items       *Schema            `json:"items"`
+		PrefixItems []*Schema          `json:"prefixItems"`
+		MaxItems    *int               `json:"maxItems"`
+		AllOf       []*Schema          `json:"allOf"`
+
+		Enum                 []interface{}      `json:"enum"`
+		Const                json.RawMessage    `json:"const"`
+		Required             []string           `json:"required"`
+		AdditionalProperties *Schema            `json:"additionalProperties"`
+		PatternProperties    map[string]*Schema `json:"patternProperties"`
+		MinProperties        *int               `json:"minProperties"`
+		MaxProperties        *int               `json:"maxProperties"`
+		MinItems             *int               `json:"minItems"`
+		UniqueItems          bool               `json:"uniqueItems"`
+		Contains             *Schema            `json:"contains"`
+		Minimum              *json.Number       `json:"minimum"`
+		Maximum              *json.Number       `json:"maximum"`
+		ExclusiveMinimum     *json.Number       `json:"exclusiveMinimum"`
+		ExclusiveMaximum     *json.Number       `json:"exclusiveMaximum"`
+		MultipleOf           *json.Number       `json:"multipleOf"`
+		MinLength            *int               `json:"minLength"`
+		MaxLength            *int               `json:"maxLength"`
+		Pattern              string             `json:"pattern"`
+		AnyOf                []*Schema          `json:"anyOf"`
+		OneOf                []*Schema          `json:"oneOf"`
+		Not                  *Schema            `json:"not"`
+		If                   *Schema            `json:"if"`
+		Then                 *Schema            `json:"then"`
+		Else                 *Schema            `json:"else"`
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&doc); err != nil {
+		return err
+	}
+
+	s.Ref = doc.Ref
+	s.Defs = doc.Defs
+	if s.Defs == nil {
+		s.Defs = doc.Definitions
+	} else {
+		for name, def := range doc.Definitions {
+			if _, ok := s.Defs[name]; !ok {
+				s.Defs[name] = def
+			}
+		}
+	}
+	s.Type = doc.Type
+	s.Format = doc.Format
+	s.Properties = doc.Properties
+	s.Items = doc.Items
+	s.PrefixItems = doc.PrefixItems
+	s.MaxItems = doc.MaxItems
+	s.AllOf = doc.AllOf
+
+	s.Enum = doc.Enum
+	if doc.Const != nil {
+		decoder := json.NewDecoder(bytes.NewReader(doc.Const))
+		decoder.UseNumber()
+		s.Const = &Value{}
+		if err := decoder.Decode(&s.Const.Value); err != nil {
+			return err
+		}
+	}
+	s.Required = doc.Required
+	s.AdditionalProperties = doc.AdditionalProperties
+	s.PatternProperties = doc.PatternProperties
+	s.MinProperties = doc.MinProperties
+	s.MaxProperties = doc.MaxProperties
+	s.MinItems = doc.MinItems
+	s.UniqueItems = doc.UniqueItems
+	s.Contains = doc.Contains
+	s.Minimum = doc.Minimum
+	s.Maximum = doc.Maximum
+	s.ExclusiveMinimum = doc.ExclusiveMinimum
+	s.ExclusiveMaximum = doc.ExclusiveMaximum
+	s.MultipleOf = doc.MultipleOf
+	s.MinLength = doc.MinLength
+	s.MaxLength = doc.MaxLength
+	s.Pattern = doc.Pattern
+	if s.Pattern != "" {
+		re, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return err
+		}
+		s.regexp = re
+	}
+	patterns := make([]string, 0, len(s.PatternProperties))
+	for pattern := range s.PatternProperties {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return err
+		}
+		s.patterns = append(s.patterns, patternProperty{pattern, re})
+	}
+	s.AnyOf = doc.AnyOf
+	s.OneOf = doc.OneOf
+	s.Not = doc.Not
+	s.If = doc.If
+	s.Then = doc.Then
+	s.Else = doc.Else
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler, keeping the order of properties.
+func (p *Properties) UnmarshalJSON(data []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if t, err := decoder.Token(); err != nil {
+		return err
+	} else if t != json.Delim('{') {
+		return fmt.Errorf("Invalid properties: %s", data)
+	}
+
+	*p = Properties{}
+	for decoder.More() {
+		t, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		var s Schema
+		if err := decoder.Decode(&s); err != nil {
+			return err
+		}
+		*p = append(*p, Property{t.(string), &s})
+	}
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (t *Types) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*t = Types{s}
+		return nil
+	}
+	var a []string
+	if err := json.Unmarshal(data, &a); err != nil {
+		return err
+	}
+	*t = Types(a)
+	return nil
+}
+
+// Has returns true if the type names include name.
+func (t Types) Has(name string) bool {
+	for _, typ := range t {
+		if typ == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Schema) setRoot(root *Schema) {
+	if s == nil {
+		return
+	}
+	s.root = root
+	for _, def := range s.Defs {
+		def.setRoot(root)
+	}
+	for _, prop := range s.Properties {
+		prop.Schema.setRoot(root)
+	}
+	s.Items.setRoot(root)
+	for _, item := range s.PrefixItems {
+		item.setRoot(root)
+	}
+	for _, subs := range [][]*Schema{s.AllOf, s.AnyOf, s.OneOf} {
+		for _, sub := range subs {
+			sub.setRoot(root)
+		}
+	}
+	for _, sub := range s.PatternProperties {
+		sub.setRoot(root)
+	}
+	for _, sub := range []*Schema{s.AdditionalProperties, s.Contains, s.Not, s.If, s.Then, s.Else} {
+		sub.setRoot(root)
+	}
+}
+
+// Property returns the schema of the property, or nil if not defined.
+func (s *Schema) Property(name string) *Schema {
+	for _, prop := range s.Properties {
+		if prop.Name == name {
+			return prop.Schema
+		}
+	}
+	return nil
+}
+
+// Resolve follows $ref and returns the referred schema.
+// Only references within the same document ("#...") are supported.
+func (s *Schema) Resolve() (*Schema, error) {
+	seen := map[*Schema]bool{}
+	for s != nil && s.Ref != "" {
+		if seen[s] {
+			return nil, fmt.Errorf("Circular $ref %q", s.Ref)
+		}
+		seen[s] = true
+
+		next, err := s.root.lookup(s.Ref)
+		if err != nil {
+			return nil, err
+		}
+		s = next
+	}
+	return s, nil
+}
+
+func (s *Schema) lookup(ref string) (*Schema, error) {
+	if !strings.HasPrefix(ref, "#") {
+		return nil, fmt.Errorf("Unsupported $ref %q", ref)
+	}
+	pointer, err := jsonpointer.New(ref[1:])
+	if err != nil {
+		return nil, fmt.Errorf("Unsupported $ref %q", ref)
+	}
+
+	current := s
+	for i := 0; i < len(pointer); i++ {
+		if current == nil {
+			break
+		}
+		token := string(pointer[i])
+		switch token {
+		case "$defs", "definitions":
+			i++
+			if i >= len(pointer) {
+				return nil, fmt.Errorf("Invalid $ref %q", ref)
+			}
+			current = current.Defs[string(pointer[i])]
+		case "properties":
+			i++
+			if i >= len(pointer) {
+				return nil, fmt.Errorf("Invalid $ref %q", ref)
+			}
+			current = current.Property(string(pointer[i]))
+		case "items":
+			current = current.Items
+		case "prefixItems":
+			i++
+			if i >= len(pointer) {
+				return nil, fmt.Errorf("Invalid $ref %q", ref)
+			}
+			n, err := strconv.Atoi(string(pointer[i]))
+			if err != nil || n < 0 || n >= len(current.PrefixItems) {
+				return nil, fmt.Errorf("Invalid $ref %q", ref)
+			}
+			current = current.PrefixItems[n]
+		default:
+			return nil, fmt.Errorf("Unsupported $ref %q", ref)
+		}
+	}
+	if current == nil {
+		return nil, fmt.Errorf("Invalid $ref %q", ref)
+	}
+	return current, nil
+}
@@ -0,0 +1,88 @@
+package jsonschema
+
+import (
+	"reflect"
+	"testing"
+)
+
+var testSchemaJSON = `{
+	"type": "object",
+	"properties": {
+		"zeta": {"type": "string", "format": "date"},
+		"alpha": {"type": ["integer", "null"]},
+		"address": {"$ref": "#/$defs/address"},
+		"tags": {"type": "array", "items": {"type": "string"}, "maxItems": 2},
+		"legacy": {"$ref": "#/definitions/legacy"},
+		"self": {"$ref": "#/properties/zeta"},
+		"loop": {"$ref": "#/$defs/loop"}
+	},
+	"$defs": {
+		"address": {
+			"type": "object",
+			"properties": {"city": {"type": "string"}, "zip": {"type": "string"}}
+		},
+		"loop": {"$ref": "#/$defs/loop"}
+	},
+	"definitions": {
+		"legacy": true
+	}
+}`
+
+func TestParse(t *testing.T) {
+	s, err := Parse([]byte(testSchemaJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, prop := range s.Properties {
+		names = append(names, prop.Name)
+	}
+	expected := []string{"zeta", "alpha", "address", "tags", "legacy", "self", "loop"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected %v, but %v", expected, names)
+	}
+
+	if !s.Type.Has("object") {
+		t.Errorf("Expected type object, but %v", s.Type)
+	}
+	if alpha := s.Property("alpha"); !reflect.DeepEqual(alpha.Type, Types{"integer", "null"}) {
+		t.Errorf("Expected %v, but %v", Types{"integer", "null"}, alpha.Type)
+	}
+	if tags := s.Property("tags"); tags.MaxItems == nil || *tags.MaxItems != 2 || !tags.Items.Type.Has("string") {
+		t.Errorf("Unexpected tags schema %#v", tags)
+	}
+	if s.Property("missing") != nil {
+		t.Errorf("Expected nil for missing property")
+	}
+}
+
+var testResolveCases = []struct {
+	property string
+	check    func(*Schema) bool
+	err      string
+}{
+	{"address", func(s *Schema) bool { return s.Property("city") != nil }, ``},
+	{"legacy", func(s *Schema) bool { return s.Boolean != nil && *s.Boolean }, ``},
+	{"self", func(s *Schema) bool { return s.Format == "date" }, ``},
+	{"zeta", func(s *Schema) bool { return s.Format == "date" }, ``},
+	{"loop", nil, `Circular $ref "#/$defs/loop"`},
+}
+
+func TestResolve(t *testing.T) {
+	s, err := Parse([]byte(testSchemaJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for caseIndex, testCase := range testResolveCases {
+		actual, err := s.Property(testCase.property).Resolve()
+		if err != nil {
+			if err.Error() != testCase.err {
+				t.Errorf("%d: Expected %v, but %v", caseIndex, testCase.err, err)
+			}
+		} else if !testCase.check(actual) {
+			t.Errorf("%d: Unexpected schema %#v", caseIndex, actual)
+		}
+	}
+}
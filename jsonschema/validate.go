@@ -0,0 +1,369 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/yukithm/json2csv/jsonpointer"
+)
+
+// ValidationError represents a keyword which the value does not satisfy.
+type ValidationError struct {
+	// Pointer is the location of the invalid value in the instance.
+	Pointer jsonpointer.JSONPointer
+	Keyword string
+	Message string
+}
+
+func (e ValidationError) Error() string {
+	return fmt.Sprintf("%q: %s: %s", e.Pointer.String(), e.Keyword, e.Message)
+}
+
+// Validate validates the value decoded from JSON and returns all errors.
+// The value should be decoded with UseNumber option.
+func (s *Schema) Validate(value interface{}) []ValidationError {
+	return s.validate(value, jsonpointer.JSONPointer{}, refStack{})
+}
+
+// IsValid returns true if the value is valid.
+func (s *Schema) IsValid(value interface{}) bool {
+	return len(s.Validate(value)) == 0
+}
+
+// refStack is the set of $ref schemas being validated with the locations in
+// the instance, which detects $ref cycles that do not consume the instance.
+type refStack map[refFrame]bool
+
+type refFrame struct {
+	schema  *Schema
+	pointer string
+}
+
+func (s *Schema) validate(value interface{}, pointer jsonpointer.JSONPointer, refs refStack) []ValidationError {
+	if s == nil {
+		return nil
+	}
+	if s.Boolean != nil {
+		if *s.Boolean {
+			return nil
+		}
+		return []ValidationError{{pointer, "false", "no value is allowed"}}
+	}
+
+	var errs []ValidationError
+	fail := func(keyword, format string, args ...interface{}) {
+		errs = append(errs, ValidationError{pointer.Clone(), keyword, fmt.Sprintf(format, args...)})
+	}
+
+	if s.Ref != "" {
+		frame := refFrame{s, pointer.String()}
+		if refs[frame] {
+			fail("$ref", "Circular $ref %q", s.Ref)
+		} else if target, err := s.root.lookup(s.Ref); err != nil {
+			fail("$ref", "%s", err)
+		} else {
+			refs[frame] = true
+			errs = append(errs, target.validate(value, pointer, refs)...)
+			delete(refs, frame)
+		}
+	}
+
+	if len(s.Type) > 0 && !s.matchType(value) {
+		fail("type", "must be %s, but %s", strings.Join(s.Type, " or "), typeOf(value))
+	}
+	if s.Enum != nil {
+		found := false
+		for _, e := range s.Enum {
+			if equal(value, e) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			fail("enum", "must be one of the enumerated values")
+		}
+	}
+	if s.Const != nil && !equal(value, s.Const.Value) {
+		fail("const", "must be %s", encode(s.Const.Value))
+	}
+
+	switch v := value.(type) {
+	case json.Number, float64:
+		n := toRat(v)
+		if n == nil {
+			break
+		}
+		if s.Minimum != nil && compare(n, *s.Minimum) < 0 {
+			fail("minimum", "must be >= %s", *s.Minimum)
+		}
+		if s.Maximum != nil && compare(n, *s.Maximum) > 0 {
+			fail("maximum", "must be <= %s", *s.Maximum)
+		}
+		if s.ExclusiveMinimum != nil && compare(n, *s.ExclusiveMinimum) <= 0 {
+			fail("exclusiveMinimum", "must be > %s", *s.ExclusiveMinimum)
+		}
+		if s.ExclusiveMaximum != nil && compare(n, *s.ExclusiveMaximum) >= 0 {
+			fail("exclusiveMaximum", "must be < %s", *s.ExclusiveMaximum)
+		}
+		if s.MultipleOf != nil {
+			if m := toRat(*s.MultipleOf); m != nil && m.Sign() != 0 && !new(big.Rat).Quo(n, m).IsInt() {
+				fail("multipleOf", "must be a multiple of %s", *s.MultipleOf)
+			}
+		}
+	case string:
+		length := utf8.RuneCountInString(v)
+		if s.MinLength != nil && length < *s.MinLength {
+			fail("minLength", "length must be >= %d", *s.MinLength)
+		}
+		if s.MaxLength != nil && length > *s.MaxLength {
+			fail("maxLength", "length must be <= %d", *s.MaxLength)
+		}
+		if s.regexp != nil && !s.regexp.MatchString(v) {
+			fail("pattern", "must match %q", s.Pattern)
+		}
+	case []interface{}:
+		errs = append(errs, s.validateArray(v, pointer, refs)...)
+	case map[string]interface{}:
+		errs = append(errs, s.validateObject(v, pointer, refs)...)
+	}
+
+	for _, sub := range s.AllOf {
+		errs = append(errs, sub.validate(value, pointer, refs)...)
+	}
+	if len(s.AnyOf) > 0 {
+		valid := false
+		for _, sub := range s.AnyOf {
+			if len(sub.validate(value, pointer, refs)) == 0 {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			fail("anyOf", "must match at least one schema")
+		}
+	}
+	if len(s.OneOf) > 0 {
+		count := 0
+		for _, sub := range s.OneOf {
+			if len(sub.validate(value, pointer, refs)) == 0 {
+				count++
+			}
+		}
+		if count != 1 {
+			fail("oneOf", "must match exactly one schema, but matched %d", count)
+		}
+	}
+	if s.Not != nil && len(s.Not.validate(value, pointer, refs)) == 0 {
+		fail("not", "must not match the schema")
+	}
+	if s.If != nil {
+		if len(s.If.validate(value, pointer, refs)) == 0 {
+			errs = append(errs, s.Then.validate(value, pointer, refs)...)
+		} else {
+			errs = append(errs, s.Else.validate(value, pointer, refs)...)
+		}
+	}
+
+	return errs
+}
+
+func (s *Schema) validateArray(items []interface{}, pointer jsonpointer.JSONPointer, refs refStack) []ValidationError {
+	var errs []ValidationError
+	fail := func(keyword, format string, args ...interface{}) {
+		errs = append(errs, ValidationError{pointer.Clone(), keyword, fmt.Sprintf(format, args...)})
+	}
+
+	for i, item := range items {
+		p := pointer.Clone()
+		p.AppendString(strconv.Itoa(i))
+		if i < len(s.PrefixItems) {
+			errs = append(errs, s.PrefixItems[i].validate(item, p, refs)...)
+		} else {
+			errs = append(errs, s.Items.validate(item, p, refs)...)
+		}
+	}
+
+	if s.MinItems != nil && len(items) < *s.MinItems {
+		fail("minItems", "must have at least %d items", *s.MinItems)
+	}
+	if s.MaxItems != nil && len(items) > *s.MaxItems {
+		fail("maxItems", "must have at most %d items", *s.MaxItems)
+	}
+	if s.UniqueItems {
+	unique:
+		for i := range items {
+			for j := i + 1; j < len(items); j++ {
+				if equal(items[i], items[j]) {
+					fail("uniqueItems", "items %d and %d are equal", i, j)
+					break unique
+				}
+			}
+		}
+	}
+	if s.Contains != nil {
+		found := false
+		for _, item := range items {
+			if len(s.Contains.validate(item, pointer, refs)) == 0 {
+				found = true
+				break
+			}
+		}
+		if !found {
+			fail("contains", "must contain an item that matches the schema")
+		}
+	}
+	return errs
+}
+
+func (s *Schema) validateObject(obj map[string]interface{}, pointer jsonpointer.JSONPointer, refs refStack) []ValidationError {
+	var errs []ValidationError
+	fail := func(keyword, format string, args ...interface{}) {
+		errs = append(errs, ValidationError{pointer.Clone(), keyword, fmt.Sprintf(format, args...)})
+	}
+
+	names := make([]string, 0, len(obj))
+	for name := range obj {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		p := pointer.Clone()
+		p.AppendString(name)
+
+		evaluated := false
+		if prop := s.Property(name); prop != nil {
+			evaluated = true
+			errs = append(errs, prop.validate(obj[name], p, refs)...)
+		}
+		for _, pp := range s.patterns {
+			if pp.re.MatchString(name) {
+				evaluated = true
+				errs = append(errs, s.PatternProperties[pp.pattern].validate(obj[name], p, refs)...)
+			}
+		}
+		if !evaluated && s.AdditionalProperties != nil {
+			errs = append(errs, s.AdditionalProperties.validate(obj[name], p, refs)...)
+		}
+	}
+
+	for _, name := range s.Required {
+		if _, ok := obj[name]; !ok {
+			p := pointer.Clone()
+			p.AppendString(name)
+			errs = append(errs, ValidationError{p, "required", "is required"})
+		}
+	}
+	if s.MinProperties != nil && len(obj) < *s.MinProperties {
+		fail("minProperties", "must have at least %d properties", *s.MinProperties)
+	}
+	if s.MaxProperties != nil && len(obj) > *s.MaxProperties {
+		fail("maxProperties", "must have at most %d properties", *s.MaxProperties)
+	}
+	return errs
+}
+
+func (s *Schema) matchType(value interface{}) bool {
+	typ := typeOf(value)
+	for _, t := range s.Type {
+		if t == typ {
+			return true
+		}
+		if t == "number" && typ == "integer" {
+			return true
+		}
+	}
+	return false
+}
+
+// typeOf returns the JSON Schema type name of the value.
+// Numbers with zero fractional part are "integer".
+func typeOf(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number, float64:
+		if n := toRat(v); n != nil && n.IsInt() {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return reflect.TypeOf(value).String()
+	}
+}
+
+func toRat(value interface{}) *big.Rat {
+	switch v := value.(type) {
+	case json.Number:
+		if r, ok := new(big.Rat).SetString(v.String()); ok {
+			return r
+		}
+	case float64:
+		return new(big.Rat).SetFloat64(v)
+	}
+	return nil
+}
+
+func compare(n *big.Rat, limit json.Number) int {
+	l := toRat(limit)
+	if l == nil {
+		return 0
+	}
+	return n.Cmp(l)
+}
+
+// equal compares JSON values. Numbers are compared by their values, and
+// other types by reflect.DeepEqual.
+func equal(a, b interface{}) bool {
+	switch av := a.(type) {
+	case json.Number, float64:
+		x, y := toRat(av), toRat(b)
+		return x != nil && y != nil && x.Cmp(y) == 0
+	case []interface{}:
+		bv, ok := b.([]interface{})
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for i := range av {
+			if !equal(av[i], bv[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]interface{}:
+		bv, ok := b.(map[string]interface{})
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for k, v := range av {
+			if w, ok := bv[k]; !ok || !equal(v, w) {
+				return false
+			}
+		}
+		return true
+	default:
+		return reflect.DeepEqual(a, b)
+	}
+}
+
+func encode(value interface{}) string {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(b)
+}
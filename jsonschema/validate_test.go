@@ -0,0 +1,210 @@
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func decode(t *testing.T, s string) interface{} {
+	d := json.NewDecoder(bytes.NewReader([]byte(s)))
+	d.UseNumber()
+	var v interface{}
+	if err := d.Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+var testValidateSchema = `{
+	"type": "object",
+	"required": ["id", "name"],
+	"properties": {
+		"id": {"type": "integer", "minimum": 1},
+		"name": {"type": "string", "minLength": 1, "maxLength": 5, "pattern": "^[a-z]+$"},
+		"score": {"type": "number", "exclusiveMaximum": 100, "multipleOf": 0.5},
+		"status": {"enum": ["active", "inactive", null]},
+		"kind": {"const": "user"},
+		"tags": {"type": "array", "items": {"type": "string"}, "uniqueItems": true, "maxItems": 3},
+		"pair": {"prefixItems": [{"type": "integer"}, {"type": "string"}], "items": false},
+		"contact": {
+			"oneOf": [
+				{"required": ["email"]},
+				{"required": ["phone"]}
+			]
+		},
+		"address": {"$ref": "#/$defs/address"}
+	},
+	"patternProperties": {"^x-": {"type": "string"}},
+	"additionalProperties": false,
+	"$defs": {
+		"address": {
+			"type": "object",
+			"properties": {"zip": {"type": "string", "pattern": "^[0-9]{3}-[0-9]{4}$"}},
+			"if": {"required": ["country"]},
+			"then": {"required": ["zip"]}
+		}
+	}
+}`
+
+var testValidateCases = []struct {
+	json     string
+	expected []string
+}{
+	{`{"id": 1, "name": "foo"}`, nil},
+	{`{"id": 1.0, "name": "foo", "score": 99.5, "status": null, "kind": "user", "x-note": "ok"}`, nil},
+	{`{"id": 0, "name": "foo"}`, []string{`"/id": minimum: must be >= 1`}},
+	{`{"id": 1.5, "name": "foo"}`, []string{`"/id": type: must be integer, but number`}},
+	{`{"name": "foo"}`, []string{`"/id": required: is required`}},
+	{`{"id": 1, "name": "Foo123"}`, []string{
+		`"/name": maxLength: length must be <= 5`,
+		`"/name": pattern: must match "^[a-z]+$"`,
+	}},
+	{`{"id": 1, "name": "foo", "score": 100}`, []string{`"/score": exclusiveMaximum: must be < 100`}},
+	{`{"id": 1, "name": "foo", "score": 0.3}`, []string{`"/score": multipleOf: must be a multiple of 0.5`}},
+	{`{"id": 1, "name": "foo", "status": "gone"}`, []string{`"/status": enum: must be one of the enumerated values`}},
+	{`{"id": 1, "name": "foo", "kind": "admin"}`, []string{`"/kind": const: must be "user"`}},
+	{`{"id": 1, "name": "foo", "tags": ["a", "a", 1, "b"]}`, []string{
+		`"/tags/2": type: must be string, but integer`,
+		`"/tags": maxItems: must have at most 3 items`,
+		`"/tags": uniqueItems: items 0 and 1 are equal`,
+	}},
+	{`{"id": 1, "name": "foo", "pair": [1, "a", true]}`, []string{`"/pair/2": false: no value is allowed`}},
+	{`{"id": 1, "name": "foo", "contact": {"email": "a", "phone": "b"}}`, []string{
+		`"/contact": oneOf: must match exactly one schema, but matched 2`,
+	}},
+	{`{"id": 1, "name": "foo", "address": {"country": "JP"}}`, []string{`"/address/zip": required: is required`}},
+	{`{"id": 1, "name": "foo", "address": {"zip": "1234567"}}`, []string{`"/address/zip": pattern: must match "^[0-9]{3}-[0-9]{4}$"`}},
+	{`{"id": 1, "name": "foo", "x-note": 1, "other": true}`, []string{
+		`"/other": false: no value is allowed`,
+		`"/x-note": type: must be string, but integer`,
+	}},
+	{`[]`, []string{`"": type: must be object, but array`}},
+}
+
+func TestValidate(t *testing.T) {
+	s, err := Parse([]byte(testValidateSchema))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for caseIndex, testCase := range testValidateCases {
+		errs := s.Validate(decode(t, testCase.json))
+		actual := make([]string, 0, len(errs))
+		for _, e := range errs {
+			actual = append(actual, e.Error())
+		}
+		if len(actual) != len(testCase.expected) {
+			t.Errorf("%d: Expected %v, but %v", caseIndex, testCase.expected, actual)
+			continue
+		}
+		for i := range actual {
+			if actual[i] != testCase.expected[i] {
+				t.Errorf("%d: Expected %v, but %v", caseIndex, testCase.expected, actual)
+				break
+			}
+		}
+		if s.IsValid(decode(t, testCase.json)) != (len(testCase.expected) == 0) {
+			t.Errorf("%d: IsValid mismatch", caseIndex)
+		}
+	}
+}
+
+func TestValidateAnyOfNot(t *testing.T) {
+	s, err := Parse([]byte(`{"anyOf": [{"type": "string"}, {"type": "null"}], "not": {"const": "x"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		json     string
+		expected string
+	}{
+		{`"a"`, ``},
+		{`null`, ``},
+		{`1`, `"": anyOf: must match at least one schema`},
+		{`"x"`, `"": not: must not match the schema`},
+	}
+	for caseIndex, testCase := range testCases {
+		errs := s.Validate(decode(t, testCase.json))
+		actual := ""
+		if len(errs) > 0 {
+			actual = errs[0].Error()
+		}
+		if actual != testCase.expected || len(errs) > 1 {
+			t.Errorf("%d: Expected %v, but %v", caseIndex, testCase.expected, errs)
+		}
+	}
+}
+
+func TestValidatePatternPropertiesOrder(t *testing.T) {
+	s, err := Parse([]byte(`{"patternProperties": {"^x": {"type": "string"}, "^x-": {"minimum": 10}, "a$": {"type": "null"}, "-": {"const": 1}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		`"/x-a": const: must be 1`,
+		`"/x-a": type: must be string, but integer`,
+		`"/x-a": minimum: must be >= 10`,
+		`"/x-a": type: must be null, but integer`,
+	}
+	for i := 0; i < 20; i++ {
+		errs := s.Validate(decode(t, `{"x-a": 2}`))
+		actual := make([]string, 0, len(errs))
+		for _, e := range errs {
+			actual = append(actual, e.Error())
+		}
+		if len(actual) != len(expected) {
+			t.Fatalf("Expected %v, but %v", expected, actual)
+		}
+		for j := range actual {
+			if actual[j] != expected[j] {
+				t.Fatalf("Expected %v, but %v", expected, actual)
+			}
+		}
+	}
+}
+
+func TestValidateUniqueItemsNonJSON(t *testing.T) {
+	s, err := Parse([]byte(`{"uniqueItems": true}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errs := s.Validate([]interface{}{[]byte("a"), []byte("b"), []byte("a")})
+	if len(errs) != 1 || errs[0].Error() != `"": uniqueItems: items 0 and 2 are equal` {
+		t.Errorf("Expected uniqueItems error, but %v", errs)
+	}
+}
+
+func TestValidateCircularRef(t *testing.T) {
+	var testCases = []struct {
+		schema   string
+		json     string
+		expected []string
+	}{
+		{`{"allOf": [{"$ref": "#"}]}`, `1`, []string{`"": $ref: Circular $ref "#"`}},
+		{`{"anyOf": [{"type": "string"}, {"$ref": "#"}]}`, `1`, []string{`"": anyOf: must match at least one schema`}},
+		{
+			`{"type": "object", "properties": {"child": {"$ref": "#"}, "name": {"type": "string"}}}`,
+			`{"child": {"child": {"name": 1}}}`,
+			[]string{`"/child/child/name": type: must be string, but integer`},
+		},
+	}
+	for caseIndex, testCase := range testCases {
+		s, err := Parse([]byte(testCase.schema))
+		if err != nil {
+			t.Fatal(err)
+		}
+		errs := s.Validate(decode(t, testCase.json))
+		actual := make([]string, 0, len(errs))
+		for _, e := range errs {
+			actual = append(actual, e.Error())
+		}
+		if strings.Join(actual, "\n") != strings.Join(testCase.expected, "\n") {
+			t.Errorf("%d: Expected %v, but %v", caseIndex, testCase.expected, actual)
+		}
+	}
+}
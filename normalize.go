@@ -0,0 +1,175 @@
+package json2csv
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/yukithm/json2csv/jsonpointer"
+)
+
+// Default keys of generated columns in normalized tables.
+const (
+	IDKey       = "/_id"
+	ParentIDKey = "/_parent_id"
+)
+
+// Table represents a table of normalized records.
+type Table struct {
+	// Name is the root name, or the parent table name and the path to the
+	// array joined by "_". (e.g. "orders_items") If the name is already
+	// used by another array, a number is appended. (e.g. "orders_items_2")
+	Name string
+
+	// Parent is the name of the parent table. It is empty for the root table.
+	Parent string
+
+	Records []KeyValue
+}
+
+// Normalizer converts JSON to tables.
+type Normalizer struct {
+	// IDKey and ParentIDKey are keys of the generated columns. It is an
+	// error if the input has the same keys.
+	IDKey       string
+	ParentIDKey string
+}
+
+type normalizer struct {
+	*Normalizer
+	tables []*Table
+	names  map[string]bool
+
+	// children maps the parent table name and the path to the child table.
+	children map[string]*Table
+}
+
+// Normalize converts JSON to tables with IDKey and ParentIDKey.
+func Normalize(data interface{}, name string) ([]Table, error) {
+	return (&Normalizer{IDKey, ParentIDKey}).Normalize(data, name)
+}
+
+// Normalize converts JSON to tables. Each array of objects is split into its
+// own child table instead of being expanded into columns. Every record has a
+// generated IDKey column, and records of child tables have a ParentIDKey
+// column which refers to IDKey of the parent record.
+func (nz *Normalizer) Normalize(data interface{}, name string) ([]Table, error) {
+	n := &normalizer{
+		Normalizer: nz,
+		names:      make(map[string]bool),
+		children:   make(map[string]*Table),
+	}
+	root := n.newTable(name, "")
+
+	v := valueOf(data)
+	switch v.Kind() {
+	case reflect.Map:
+		if v.Len() > 0 {
+			if err := n.addRecord(root, v, 0); err != nil {
+				return nil, err
+			}
+		}
+	case reflect.Slice:
+		if isObjectArray(v) {
+			for i := 0; i < v.Len(); i++ {
+				if err := n.addRecord(root, v.Index(i), 0); err != nil {
+					return nil, err
+				}
+			}
+		} else if v.Len() > 0 {
+			if err := n.addRecord(root, v, 0); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, errors.New("Unsupported JSON structure.")
+	}
+
+	tables := make([]Table, 0, len(n.tables))
+	for _, t := range n.tables {
+		tables = append(tables, *t)
+	}
+	return tables, nil
+}
+
+// newTable adds a table with the name, or the name and a number if the name
+// is already used.
+func (n *normalizer) newTable(name, parent string) *Table {
+	unique := name
+	for i := 2; n.names[unique]; i++ {
+		unique = name + "_" + strconv.Itoa(i)
+	}
+	t := &Table{Name: unique, Parent: parent, Records: []KeyValue{}}
+	n.tables = append(n.tables, t)
+	n.names[unique] = true
+	return t
+}
+
+// childTable returns the table of the array at the key in the parent records.
+func (n *normalizer) childTable(parent *Table, key jsonpointer.JSONPointer) *Table {
+	path := parent.Name + key.String()
+	if t, ok := n.children[path]; ok {
+		return t
+	}
+	t := n.newTable(parent.Name+"_"+strings.Join(key.Strings(), "_"), parent.Name)
+	n.children[path] = t
+	return t
+}
+
+func (n *normalizer) addRecord(t *Table, obj interface{}, parentID int) error {
+	id := len(t.Records) + 1
+	record := KeyValue{n.IDKey: json.Number(strconv.Itoa(id))}
+	if t.Parent != "" {
+		record[n.ParentIDKey] = json.Number(strconv.Itoa(parentID))
+	}
+	t.Records = append(t.Records, record)
+
+	return n.walk(t, id, record, obj, jsonpointer.JSONPointer{})
+}
+
+func (n *normalizer) walk(t *Table, id int, out KeyValue, obj interface{}, key jsonpointer.JSONPointer) error {
+	value := valueOf(obj)
+	if _, ok := scalarOf(value); ok {
+		return n.flattenValue(out, value, key)
+	}
+	switch value.Kind() {
+	case reflect.Map:
+		for _, k := range sortedMapKeys(value) {
+			pointer := key.Clone()
+			pointer.AppendString(mapKeyString(k))
+			if err := n.walk(t, id, out, value.MapIndex(k), pointer); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Slice:
+		if isObjectArray(value) {
+			child := n.childTable(t, key)
+			for i := 0; i < value.Len(); i++ {
+				if err := n.addRecord(child, value.Index(i), id); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		for i := 0; i < value.Len(); i++ {
+			pointer := key.Clone()
+			pointer.AppendString(strconv.Itoa(i))
+			if err := n.walk(t, id, out, value.Index(i), pointer); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return n.flattenValue(out, value, key)
+}
+
+func (n *normalizer) flattenValue(out KeyValue, value reflect.Value, key jsonpointer.JSONPointer) error {
+	if k := key.String(); k == n.IDKey || k == n.ParentIDKey {
+		return fmt.Errorf("Key %s in the input collides with the generated column", k)
+	}
+	return _flatten(out, value, key, false)
+}
@@ -0,0 +1,139 @@
+package json2csv
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var testNormalizeCases = []struct {
+	json     string
+	expected []Table
+	err      string
+}{
+	{
+		`[
+			{"id": 1, "items": [{"sku": "a", "parts": [{"no": 1}]}, {"sku": "b"}], "tags": ["x"]},
+			{"id": 2, "items": [{"sku": "c"}], "customer": {"addresses": [{"city": "Tokyo"}]}}
+		]`,
+		[]Table{
+			{"orders", "", []KeyValue{
+				{IDKey: json.Number("1"), "/id": json.Number("1"), "/tags/0": "x"},
+				{IDKey: json.Number("2"), "/id": json.Number("2")},
+			}},
+			{"orders_items", "orders", []KeyValue{
+				{IDKey: json.Number("1"), ParentIDKey: json.Number("1"), "/sku": "a"},
+				{IDKey: json.Number("2"), ParentIDKey: json.Number("1"), "/sku": "b"},
+				{IDKey: json.Number("3"), ParentIDKey: json.Number("2"), "/sku": "c"},
+			}},
+			{"orders_items_parts", "orders_items", []KeyValue{
+				{IDKey: json.Number("1"), ParentIDKey: json.Number("1"), "/no": json.Number("1")},
+			}},
+			{"orders_customer_addresses", "orders", []KeyValue{
+				{IDKey: json.Number("1"), ParentIDKey: json.Number("2"), "/city": "Tokyo"},
+			}},
+		},
+		``,
+	},
+	{
+		`{"id": 1, "items": []}`,
+		[]Table{
+			{"orders", "", []KeyValue{
+				{IDKey: json.Number("1"), "/id": json.Number("1")},
+			}},
+		},
+		``,
+	},
+	{
+		`[]`,
+		[]Table{{"orders", "", []KeyValue{}}},
+		``,
+	},
+	{
+		`{"a_b": [{"x": 1}], "a": {"b": [{"y": 2}]}}`,
+		[]Table{
+			{"orders", "", []KeyValue{
+				{IDKey: json.Number("1")},
+			}},
+			{"orders_a_b", "orders", []KeyValue{
+				{IDKey: json.Number("1"), ParentIDKey: json.Number("1"), "/y": json.Number("2")},
+			}},
+			{"orders_a_b_2", "orders", []KeyValue{
+				{IDKey: json.Number("1"), ParentIDKey: json.Number("1"), "/x": json.Number("1")},
+			}},
+		},
+		``,
+	},
+	{`[{"_id": "x", "items": [{"n": 1}]}]`, nil, `Key /_id in the input collides with the generated column`},
+	{`{"items": [{"_parent_id": 1}]}`, nil, `Key /_parent_id in the input collides with the generated column`},
+	{`"foo"`, nil, `Unsupported JSON structure.`},
+}
+
+func TestNormalize(t *testing.T) {
+	for caseIndex, testCase := range testNormalizeCases {
+		obj, err := json2obj(testCase.json)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		actual, err := Normalize(obj, "orders")
+		if err != nil {
+			if err.Error() != testCase.err {
+				t.Errorf("%d: Expected %v, but %v", caseIndex, testCase.err, err)
+			}
+		} else if !reflect.DeepEqual(testCase.expected, actual) {
+			t.Errorf("%d: Expected %#v, but %#v", caseIndex, testCase.expected, actual)
+		}
+	}
+}
+
+func TestNormalizerKeys(t *testing.T) {
+	obj, err := json2obj(`[{"_id": "x", "items": [{"n": 1}]}]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Table{
+		{"orders", "", []KeyValue{
+			{"/row_id": json.Number("1"), "/_id": "x"},
+		}},
+		{"orders_items", "orders", []KeyValue{
+			{"/row_id": json.Number("1"), "/parent_row_id": json.Number("1"), "/n": json.Number("1")},
+		}},
+	}
+
+	actual, err := (&Normalizer{"/row_id", "/parent_row_id"}).Normalize(obj, "orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Expected %#v, but %#v", expected, actual)
+	}
+}
+
+func TestNormalizeNonJSONValues(t *testing.T) {
+	obj := map[interface{}]interface{}{
+		1:      "one",
+		"data": []byte("ab"),
+		"at":   time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		"items": []interface{}{
+			map[interface{}]interface{}{true: "yes"},
+		},
+	}
+	expected := []Table{
+		{"orders", "", []KeyValue{
+			{IDKey: json.Number("1"), "/1": "one", "/data": "YWI=", "/at": "2020-01-02T03:04:05Z"},
+		}},
+		{"orders_items", "orders", []KeyValue{
+			{IDKey: json.Number("1"), ParentIDKey: json.Number("1"), "/true": "yes"},
+		}},
+	}
+
+	actual, err := Normalize(obj, "orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Expected %#v, but %#v", expected, actual)
+	}
+}
@@ -0,0 +1,220 @@
+package json2csv
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/parquet-go/parquet-go/compress"
+	"github.com/parquet-go/parquet-go/compress/snappy"
+	"github.com/parquet-go/parquet-go/compress/uncompressed"
+	"github.com/parquet-go/parquet-go/compress/zstd"
+)
+
+// ParquetCompression represents the compression codec of Parquet columns.
+type ParquetCompression uint
+
+// Parquet compression codecs
+const (
+	SnappyCompression ParquetCompression = iota
+	ZstdCompression
+	NoCompression
+)
+
+// DefaultParquetRowGroupSize is the default maximum number of rows per row group.
+const DefaultParquetRowGroupSize = 100000
+
+// ParquetWriter writes Apache Parquet data.
+//
+// Each column is optional, so missing and null values are represented as
+// nulls. Column types are inferred from the values.
+type ParquetWriter struct {
+	w            io.Writer
+	HeaderStyle  KeyStyle
+	Compression  ParquetCompression
+	RowGroupSize int
+}
+
+// NewParquetWriter returns new ParquetWriter with JSONPointerStyle and SnappyCompression.
+func NewParquetWriter(w io.Writer) *ParquetWriter {
+	return &ParquetWriter{
+		w,
+		JSONPointerStyle,
+		SnappyCompression,
+		DefaultParquetRowGroupSize,
+	}
+}
+
+// WriteParquet writes Parquet data.
+func (w *ParquetWriter) WriteParquet(results []KeyValue) error {
+	pts, err := sortedPointers(results)
+	if err != nil {
+		return err
+	}
+	if len(pts) == 0 {
+		return errors.New("No columns to write")
+	}
+	keys := pts.Strings()
+	columns := uniqueColumnNames(headerOf(pts, w.HeaderStyle))
+	types := InferColumnTypes(results, keys)
+
+	for i, key := range keys {
+		if types[i] == BigIntegerColumn {
+			unsigned, err := isUnsignedColumn(results, key)
+			if err != nil {
+				return err
+			}
+			if !unsigned {
+				types[i] = TextColumn
+			}
+		}
+	}
+
+	group := make(parquet.Group, len(columns))
+	for i, column := range columns {
+		group[column] = parquet.Optional(parquetNode(types[i]))
+	}
+	schema := parquet.NewSchema("json2csv", orderedGroup(group, columns))
+
+	options := []parquet.WriterOption{schema, parquet.Compression(w.codec())}
+	if w.RowGroupSize > 0 {
+		options = append(options, parquet.MaxRowsPerRowGroup(int64(w.RowGroupSize)))
+	}
+	pw := parquet.NewWriter(w.w, options...)
+
+	row := make(parquet.Row, len(keys))
+	for _, result := range results {
+		for i, key := range keys {
+			row[i] = parquetValue(result[key], types[i], i)
+		}
+		if _, err := pw.WriteRows([]parquet.Row{row}); err != nil {
+			return err
+		}
+	}
+
+	return pw.Close()
+}
+
+// parquetGroup is a group node whose fields are in the order of the columns,
+// while parquet.Group orders fields by name.
+type parquetGroup struct {
+	parquet.Group
+	fields []parquet.Field
+}
+
+func (g parquetGroup) Fields() []parquet.Field { return g.fields }
+
+func orderedGroup(group parquet.Group, columns []string) parquetGroup {
+	index := make(map[string]int, len(columns))
+	for i, column := range columns {
+		index[column] = i
+	}
+	fields := group.Fields()
+	sort.Slice(fields, func(i, j int) bool {
+		return index[fields[i].Name()] < index[fields[j].Name()]
+	})
+	return parquetGroup{group, fields}
+}
+
+// isUnsignedColumn reports whether the integers out of the range of int64 in
+// the column fit in uint64, which are written as UINT_64. Otherwise they are
+// written as strings. It is an error if the column also has negative values.
+func isUnsignedColumn(results []KeyValue, key string) (bool, error) {
+	negative := false
+	for _, result := range results {
+		switch n := result[key].(type) {
+		case int64:
+			negative = negative || n < 0
+		case json.Number:
+			if strings.HasPrefix(n.String(), "-") {
+				negative = true
+			} else if _, err := strconv.ParseUint(n.String(), 10, 64); err != nil {
+				return false, nil
+			}
+		}
+	}
+	if negative {
+		return false, fmt.Errorf("Column %s has integers out of the range of int64 and uint64", key)
+	}
+	return true, nil
+}
+
+func (w *ParquetWriter) codec() compress.Codec {
+	switch w.Compression {
+	case ZstdCompression:
+		return &zstd.Codec{}
+	case NoCompression:
+		return &uncompressed.Codec{}
+	default:
+		return &snappy.Codec{}
+	}
+}
+
+func parquetNode(t ColumnType) parquet.Node {
+	switch t {
+	case BooleanColumn:
+		return parquet.Leaf(parquet.BooleanType)
+	case IntegerColumn:
+		return parquet.Leaf(parquet.Int64Type)
+	case BigIntegerColumn:
+		return parquet.Uint(64)
+	case FloatColumn:
+		return parquet.Leaf(parquet.DoubleType)
+	default:
+		return parquet.String()
+	}
+}
+
+func parquetValue(value interface{}, t ColumnType, columnIndex int) parquet.Value {
+	if value == nil {
+		return parquet.NullValue().Level(0, 0, columnIndex)
+	}
+
+	var v parquet.Value
+	switch t {
+	case BooleanColumn:
+		v = parquet.BooleanValue(value.(bool))
+	case IntegerColumn:
+		switch n := value.(type) {
+		case json.Number:
+			i, _ := n.Int64()
+			v = parquet.Int64Value(i)
+		case int64:
+			v = parquet.Int64Value(n)
+		case uint64:
+			v = parquet.Int64Value(int64(n))
+		}
+	case BigIntegerColumn:
+		var u uint64
+		switch n := value.(type) {
+		case json.Number:
+			u, _ = strconv.ParseUint(n.String(), 10, 64)
+		case int64:
+			u = uint64(n)
+		case uint64:
+			u = n
+		}
+		// UINT_64 is stored in the bits of INT64
+		v = parquet.Int64Value(int64(u))
+	case FloatColumn:
+		switch n := value.(type) {
+		case json.Number:
+			f, _ := n.Float64()
+			v = parquet.DoubleValue(f)
+		case int64:
+			v = parquet.DoubleValue(float64(n))
+		case uint64:
+			v = parquet.DoubleValue(float64(n))
+		case float64:
+			v = parquet.DoubleValue(n)
+		}
+	default:
+		v = parquet.ByteArrayValue([]byte(toString(value)))
+	}
+	return v.Level(0, 1, columnIndex)
+}
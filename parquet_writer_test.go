@@ -0,0 +1,153 @@
+package json2csv
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+func TestWriteParquet(t *testing.T) {
+	results := []KeyValue{
+		{"/id": json.Number("1"), "/name": "foo", "/score": json.Number("1.5"), "/active": true},
+		{"/id": json.Number("2"), "/name": nil},
+	}
+
+	for _, compression := range []ParquetCompression{SnappyCompression, ZstdCompression, NoCompression} {
+		b := &bytes.Buffer{}
+		w := NewParquetWriter(b)
+		w.HeaderStyle = DotNotationStyle
+		w.Compression = compression
+		if err := w.WriteParquet(results); err != nil {
+			t.Fatal(err)
+		}
+
+		f, err := parquet.OpenFile(bytes.NewReader(b.Bytes()), int64(b.Len()))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var columns []string
+		for _, field := range f.Schema().Fields() {
+			columns = append(columns, field.Name())
+		}
+		expectedColumns := []string{"active", "id", "name", "score"}
+		if !reflect.DeepEqual(columns, expectedColumns) {
+			t.Errorf("%d: Expected %v, but %v", compression, expectedColumns, columns)
+		}
+
+		r := parquet.NewReader(f)
+		rows := make([]parquet.Row, 2)
+		n, err := r.ReadRows(rows)
+		if err != nil && err != io.EOF {
+			t.Fatal(err)
+		}
+		if n != 2 {
+			t.Fatalf("%d: Expected 2 rows, but %d", compression, n)
+		}
+
+		actual := [][]interface{}{}
+		for _, row := range rows {
+			values := []interface{}{}
+			for _, v := range row {
+				switch {
+				case v.IsNull():
+					values = append(values, nil)
+				case v.Kind() == parquet.Boolean:
+					values = append(values, v.Boolean())
+				case v.Kind() == parquet.Int64:
+					values = append(values, v.Int64())
+				case v.Kind() == parquet.Double:
+					values = append(values, v.Double())
+				default:
+					values = append(values, v.String())
+				}
+			}
+			actual = append(actual, values)
+		}
+		expected := [][]interface{}{
+			{true, int64(1), "foo", 1.5},
+			{nil, int64(2), nil, nil},
+		}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("%d: Expected %v, but %v", compression, expected, actual)
+		}
+	}
+}
+
+func TestWriteParquetColumns(t *testing.T) {
+	results := []KeyValue{
+		{"/z": json.Number("1"), "/a/b": "x", "/u": uint64(math.MaxUint64)},
+		{"/z": json.Number("2"), "/a/b": "y", "/u": uint64(1)},
+	}
+
+	b := &bytes.Buffer{}
+	w := NewParquetWriter(b)
+	w.HeaderStyle = DotNotationStyle
+	if err := w.WriteParquet(results); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := parquet.OpenFile(bytes.NewReader(b.Bytes()), int64(b.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var columns []string
+	for _, field := range f.Schema().Fields() {
+		columns = append(columns, field.Name())
+	}
+	expectedColumns := []string{"u", "z", "a.b"}
+	if !reflect.DeepEqual(columns, expectedColumns) {
+		t.Errorf("Expected %v, but %v", expectedColumns, columns)
+	}
+	if logical := f.Schema().Fields()[0].Type().LogicalType(); logical == nil || logical.Integer == nil || logical.Integer.IsSigned {
+		t.Errorf("Expected UINT_64, but %v", logical)
+	}
+
+	rows := make([]parquet.Row, 2)
+	if _, err := parquet.NewReader(f).ReadRows(rows); err != nil && err != io.EOF {
+		t.Fatal(err)
+	}
+	if u := rows[0][0].Uint64(); u != math.MaxUint64 {
+		t.Errorf("Expected %d, but %d", uint64(math.MaxUint64), u)
+	}
+	if s := rows[1][2].String(); s != "y" {
+		t.Errorf("Expected y, but %s", s)
+	}
+
+	results = append(results, KeyValue{"/u": int64(-1)})
+	if err := NewParquetWriter(&bytes.Buffer{}).WriteParquet(results); err == nil {
+		t.Error("Expected error")
+	}
+}
+
+func TestWriteParquetBigIntegers(t *testing.T) {
+	results := []KeyValue{
+		{"/n": json.Number("123456789012345678901234567890"), "/u": json.Number("18446744073709551615")},
+		{"/n": json.Number("1"), "/u": json.Number("1")},
+	}
+
+	b := &bytes.Buffer{}
+	if err := NewParquetWriter(b).WriteParquet(results); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := parquet.OpenFile(bytes.NewReader(b.Bytes()), int64(b.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := make([]parquet.Row, 2)
+	if _, err := parquet.NewReader(f).ReadRows(rows); err != nil && err != io.EOF {
+		t.Fatal(err)
+	}
+	if s := rows[0][0].String(); s != "123456789012345678901234567890" {
+		t.Errorf("Expected 123456789012345678901234567890, but %s", s)
+	}
+	if u := rows[0][1].Uint64(); u != math.MaxUint64 {
+		t.Errorf("Expected %d, but %d", uint64(math.MaxUint64), u)
+	}
+}
@@ -0,0 +1,161 @@
+package json2csv
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"strings"
+
+	"github.com/yukithm/json2csv/jsonpointer"
+)
+
+// Unpivot turns the columns matching the pattern into rows ("melt"). Each
+// result becomes a row per matched column in the results, with the other
+// columns repeated, the matched key at keyKey and the value at valueKey.
+// Every result has a row per column matched in any of the results, and the
+// row has no value at valueKey if the column is missing in the result.
+//
+// The pattern is a JSON Pointer whose tokens may be "*" which matches any
+// token, and it matches keys starting with it. The key is the unescaped
+// tokens of the key from the first "*" (or after the pattern) joined by "/",
+// e.g. "cpu" and "disk/read" for "/metrics/*". Since the tokens are not
+// escaped, Pivot splits a token containing "/" into nested keys.
+//
+// It returns an error if the pattern matches no column of the results.
+func Unpivot(results []KeyValue, pattern string, keyKey, valueKey string) ([]KeyValue, error) {
+	p, err := jsonpointer.New(pattern)
+	if err != nil {
+		return nil, err
+	}
+	pts, err := sortedPointers(results)
+	if err != nil {
+		return nil, err
+	}
+
+	var matched pointers
+	for _, pointer := range pts {
+		if matchPointer(p, pointer) {
+			matched = append(matched, pointer)
+		}
+	}
+	if len(matched) == 0 && len(results) > 0 {
+		return nil, fmt.Errorf("No column matches %q", pattern)
+	}
+
+	unpivoted := make([]KeyValue, 0, len(results)*len(matched))
+	for _, result := range results {
+		ids := KeyValue{}
+		for key, value := range result {
+			ids[key] = value
+		}
+		for _, pointer := range matched {
+			delete(ids, pointer.String())
+		}
+		for _, key := range []string{keyKey, valueKey} {
+			if _, ok := ids[key]; ok {
+				return nil, fmt.Errorf("Column %q already exists", key)
+			}
+		}
+
+		for _, pointer := range matched {
+			row := make(KeyValue, len(ids)+2)
+			for key, value := range ids {
+				row[key] = value
+			}
+			row[keyKey] = strings.Join(pointer[wildcardIndex(p):].Strings(), "/")
+			if value, ok := result[pointer.String()]; ok {
+				row[valueKey] = value
+			}
+			unpivoted = append(unpivoted, row)
+		}
+	}
+	return unpivoted, nil
+}
+
+// matchPointer reports whether the pointer starts with the pattern. A
+// pattern without "*" matches keys under it.
+func matchPointer(pattern, pointer jsonpointer.JSONPointer) bool {
+	n := pattern.Len()
+	if pointer.Len() < n || (pointer.Len() == n && wildcardIndex(pattern) == n) {
+		return false
+	}
+	for i, token := range pattern {
+		if token != "*" && token != pointer[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// wildcardIndex returns the index of the first "*" token, or the length.
+func wildcardIndex(pattern jsonpointer.JSONPointer) int {
+	for i, token := range pattern {
+		if token == "*" {
+			return i
+		}
+	}
+	return pattern.Len()
+}
+
+// Pivot turns rows into columns, the reverse of Unpivot. Results with equal
+// values of the other columns are merged into a result in the order of
+// appearance, where the value at valueKey is put at the key which is prefix
+// and the value at keyKey joined by "/". (e.g. "/metrics/cpu")
+// The value at keyKey is split into tokens by "/", so "disk/read" is put at
+// "/metrics/disk/read", and a key "a/b" unpivoted from "/metrics/a~1b" is
+// put at "/metrics/a/b". Results without keyKey or with null are merged
+// without adding a column.
+func Pivot(results []KeyValue, keyKey, valueKey, prefix string) ([]KeyValue, error) {
+	base, err := jsonpointer.New(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	type digest [sha256.Size]byte
+	index := make(map[digest]int)
+	var pivoted []KeyValue
+	sources := map[string]bool{} // group index and key to find duplicates
+
+	h := sha256.New()
+	for _, result := range results {
+		ids := KeyValue{}
+		for key, value := range result {
+			if key != keyKey && key != valueKey {
+				ids[key] = value
+			}
+		}
+		h.Reset()
+		hashRecord(h, ids)
+		var d digest
+		h.Sum(d[:0])
+
+		i, ok := index[d]
+		if !ok {
+			i = len(pivoted)
+			index[d] = i
+			pivoted = append(pivoted, ids)
+		}
+
+		name, ok := result[keyKey]
+		if !ok || name == nil {
+			continue
+		}
+		pointer := base.Clone()
+		for _, token := range strings.Split(toString(name), "/") {
+			pointer.AppendString(token)
+		}
+		key := pointer.String()
+
+		if _, ok := ids[key]; ok {
+			return nil, fmt.Errorf("Column %q already exists", key)
+		}
+		source := fmt.Sprintf("%d:%s", i, key)
+		if sources[source] {
+			return nil, fmt.Errorf("Duplicate values for %q", key)
+		}
+		sources[source] = true
+		if value, ok := result[valueKey]; ok {
+			pivoted[i][key] = value
+		}
+	}
+	return pivoted, nil
+}
@@ -0,0 +1,137 @@
+package json2csv
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestUnpivot(t *testing.T) {
+	obj, err := json2obj(`[
+		{"host": "a", "metrics": {"cpu": 1, "mem": 2}},
+		{"host": "b", "metrics": {"cpu": 3, "disk": {"read": 4}}, "note": "x"}
+	]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := JSON2CSV(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []KeyValue{
+		{"/host": "a", "/key": "cpu", "/value": json.Number("1")},
+		{"/host": "a", "/key": "mem", "/value": json.Number("2")},
+		{"/host": "a", "/key": "disk/read"},
+		{"/host": "b", "/note": "x", "/key": "cpu", "/value": json.Number("3")},
+		{"/host": "b", "/note": "x", "/key": "mem"},
+		{"/host": "b", "/note": "x", "/key": "disk/read", "/value": json.Number("4")},
+	}
+	for _, pattern := range []string{"/metrics/*", "/metrics"} {
+		actual, err := Unpivot(results, pattern, "/key", "/value")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(expected, actual) {
+			t.Errorf("%s: Expected %#v, but %#v", pattern, expected, actual)
+		}
+	}
+
+	actual, err := Unpivot(results, "/metrics/*/read", "/key", "/value")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = []KeyValue{
+		{"/host": "a", "/metrics/cpu": json.Number("1"), "/metrics/mem": json.Number("2"), "/key": "disk/read"},
+		{"/host": "b", "/note": "x", "/metrics/cpu": json.Number("3"), "/key": "disk/read", "/value": json.Number("4")},
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Expected %#v, but %#v", expected, actual)
+	}
+
+	if _, err := Unpivot(results, "/metrics/*", "/host", "/value"); err == nil {
+		t.Error("Expected error for existing column")
+	}
+	for _, pattern := range []string{"/missing/*", "/host/*", "/metrics/cpu/*"} {
+		if _, err := Unpivot(results, pattern, "/key", "/value"); err == nil {
+			t.Errorf("%s: Expected error for no matched column", pattern)
+		}
+	}
+	if actual, err := Unpivot(nil, "/missing/*", "/key", "/value"); err != nil || len(actual) != 0 {
+		t.Errorf("Expected no results, but %v, %v", actual, err)
+	}
+}
+
+func TestPivot(t *testing.T) {
+	obj, err := json2obj(`[
+		{"host": "a", "key": "cpu", "value": 1},
+		{"host": "b", "key": "cpu", "value": 3},
+		{"host": "a", "key": "disk/read", "value": 2},
+		{"host": "a", "key": "mem"},
+		{"host": "c"},
+		{"host": "b", "key": 10, "value": "ten"}
+	]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := JSON2CSV(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// null keys add no column
+	results = append(results, KeyValue{"/host": "a", "/key": nil, "/value": json.Number("9")})
+
+	expected := []KeyValue{
+		{"/host": "a", "/metrics/cpu": json.Number("1"), "/metrics/disk/read": json.Number("2")},
+		{"/host": "b", "/metrics/cpu": json.Number("3"), "/metrics/10": "ten"},
+		{"/host": "c"},
+	}
+	actual, err := Pivot(results, "/key", "/value", "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Expected %#v, but %#v", expected, actual)
+	}
+
+	// round trip
+	unpivoted, err := Unpivot(expected, "/metrics/*", "/key", "/value")
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual, err = Pivot(unpivoted, "/key", "/value", "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Expected %#v, but %#v", expected, actual)
+	}
+
+	// keys are not escaped, so "/" in a token is not round-tripped
+	escaped := []KeyValue{{"/host": "a", "/m/a~1b": json.Number("1"), "/m/c~0d/e": json.Number("2")}}
+	unpivoted, err = Unpivot(escaped, "/m/*", "/key", "/value")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := []interface{}{unpivoted[0]["/key"], unpivoted[1]["/key"]}
+	if !reflect.DeepEqual([]interface{}{"a/b", "c~d/e"}, keys) {
+		t.Errorf("Unexpected keys %v", keys)
+	}
+	actual, err = Pivot(unpivoted, "/key", "/value", "/m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = []KeyValue{{"/host": "a", "/m/a/b": json.Number("1"), "/m/c~0d/e": json.Number("2")}}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Expected %#v, but %#v", expected, actual)
+	}
+
+	results = append(results, KeyValue{"/host": "a", "/key": "cpu", "/value": json.Number("5")})
+	if _, err := Pivot(results, "/key", "/value", "/metrics"); err == nil {
+		t.Error("Expected error for duplicate values")
+	}
+	conflict := []KeyValue{{"/host": "a", "/key": "host", "/value": json.Number("1")}}
+	if _, err := Pivot(conflict, "/key", "/value", ""); err == nil {
+		t.Error("Expected error for existing column")
+	}
+}
@@ -0,0 +1,139 @@
+package json2csv
+
+import (
+	"encoding/json"
+	"math/big"
+	"sort"
+	"strconv"
+	"unicode/utf8"
+)
+
+// DefaultSchemaSamples is the maximum number of sample values in ColumnSchema.
+const DefaultSchemaSamples = 5
+
+// Schema represents the inferred schema of records.
+type Schema struct {
+	Records int            `json:"records"`
+	Columns []ColumnSchema `json:"columns"`
+}
+
+// ColumnSchema represents the inferred type and statistics of a column.
+type ColumnSchema struct {
+	Pointer      string        `json:"pointer"`
+	Type         string        `json:"type"`
+	Types        []string      `json:"types"`
+	Count        int           `json:"count"`
+	NullCount    int           `json:"null_count"`
+	MissingCount int           `json:"missing_count"`
+	Min          json.Number   `json:"min,omitempty"`
+	Max          json.Number   `json:"max,omitempty"`
+	MinLength    *int          `json:"min_length,omitempty"`
+	MaxLength    *int          `json:"max_length,omitempty"`
+	Samples      []interface{} `json:"samples"`
+}
+
+// InferSchema returns the schema of the results.
+// Columns are ordered in the same way as the CSV header.
+func InferSchema(results []KeyValue) (*Schema, error) {
+	pts, err := sortedPointers(results)
+	if err != nil {
+		return nil, err
+	}
+	keys := pts.Strings()
+	types := InferColumnTypes(results, keys)
+
+	schema := &Schema{
+		Records: len(results),
+		Columns: make([]ColumnSchema, 0, len(keys)),
+	}
+	for i, key := range keys {
+		column := inferColumnSchema(results, key)
+		column.Type = types[i].String()
+		schema.Columns = append(schema.Columns, column)
+	}
+	return schema, nil
+}
+
+func inferColumnSchema(results []KeyValue, key string) ColumnSchema {
+	column := ColumnSchema{Pointer: key, Samples: []interface{}{}}
+	observed := map[string]bool{}
+	samples := map[string]bool{}
+	var min, max *big.Rat
+
+	for _, result := range results {
+		value, ok := result[key]
+		if !ok {
+			column.MissingCount++
+			continue
+		}
+
+		typ := jsonTypeOf(value)
+		observed[typ] = true
+		if value == nil {
+			column.NullCount++
+			continue
+		}
+		column.Count++
+
+		switch typ {
+		case "number":
+			n := toNumber(value)
+			if r, ok := new(big.Rat).SetString(n.String()); ok {
+				if min == nil || r.Cmp(min) < 0 {
+					min, column.Min = r, n
+				}
+				if max == nil || r.Cmp(max) > 0 {
+					max, column.Max = r, n
+				}
+			}
+		case "string":
+			length := utf8.RuneCountInString(value.(string))
+			if column.MinLength == nil || length < *column.MinLength {
+				column.MinLength = &length
+			}
+			if column.MaxLength == nil || length > *column.MaxLength {
+				column.MaxLength = &length
+			}
+		}
+
+		if len(column.Samples) < DefaultSchemaSamples {
+			s := toString(value)
+			if !samples[s] {
+				samples[s] = true
+				column.Samples = append(column.Samples, value)
+			}
+		}
+	}
+
+	column.Types = make([]string, 0, len(observed))
+	for typ := range observed {
+		column.Types = append(column.Types, typ)
+	}
+	sort.Strings(column.Types)
+	return column
+}
+
+// jsonTypeOf returns the JSON type name of the flattened value.
+func jsonTypeOf(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number, int64, uint64, float64:
+		return "number"
+	default:
+		return "string"
+	}
+}
+
+func toNumber(value interface{}) json.Number {
+	switch v := value.(type) {
+	case json.Number:
+		return v
+	case float64:
+		return json.Number(strconv.FormatFloat(v, 'f', -1, 64))
+	default:
+		return json.Number(toString(v))
+	}
+}
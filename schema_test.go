@@ -0,0 +1,75 @@
+package json2csv
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestInferSchema(t *testing.T) {
+	obj, err := json2obj(`[
+		{"id": 1, "name": "foo", "score": 10, "active": true},
+		{"id": 2, "name": "barbaz", "score": "n/a", "active": null},
+		{"id": 3, "name": "foo", "score": 2.5}
+	]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := (&Converter{KeepNulls: true}).Convert(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actual, err := InferSchema(results)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	three, six := 3, 6
+	expected := &Schema{
+		Records: 3,
+		Columns: []ColumnSchema{
+			{
+				Pointer:      "/active",
+				Type:         "boolean",
+				Types:        []string{"boolean", "null"},
+				Count:        1,
+				NullCount:    1,
+				MissingCount: 1,
+				Samples:      []interface{}{true},
+			},
+			{
+				Pointer: "/id",
+				Type:    "integer",
+				Types:   []string{"number"},
+				Count:   3,
+				Min:     json.Number("1"),
+				Max:     json.Number("3"),
+				Samples: []interface{}{json.Number("1"), json.Number("2"), json.Number("3")},
+			},
+			{
+				Pointer:   "/name",
+				Type:      "text",
+				Types:     []string{"string"},
+				Count:     3,
+				MinLength: &three,
+				MaxLength: &six,
+				Samples:   []interface{}{"foo", "barbaz"},
+			},
+			{
+				Pointer:   "/score",
+				Type:      "text",
+				Types:     []string{"number", "string"},
+				Count:     3,
+				Min:       json.Number("2.5"),
+				Max:       json.Number("10"),
+				MinLength: &three,
+				MaxLength: &three,
+				Samples:   []interface{}{json.Number("10"), "n/a", json.Number("2.5")},
+			},
+		},
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Expected %#v, but %#v", expected, actual)
+	}
+}
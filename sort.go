@@ -0,0 +1,138 @@
+package json2csv
+
+import (
+	"math/big"
+	"sort"
+	"strings"
+)
+
+// SortKey is a column to sort results by.
+type SortKey struct {
+	// Key is the JSON Pointer of the column.
+	Key        string
+	Descending bool
+}
+
+// MissingOrder represents where missing and null values are placed.
+type MissingOrder uint
+
+// Missing orders
+const (
+	MissingLast MissingOrder = iota
+	MissingFirst
+)
+
+// SortResults sorts the results stably by the keys in priority order.
+// Numbers are compared by their exact values and strings lexically. Values
+// of different types are ordered as booleans, numbers and strings. Missing
+// and null values are placed by missing regardless of Descending.
+func SortResults(results []KeyValue, keys []SortKey, missing MissingOrder) {
+	if len(keys) == 0 {
+		return
+	}
+
+	rows := make([]sortRow, len(results))
+	for i, result := range results {
+		rows[i] = newSortRow(result, keys)
+	}
+
+	sortRows(rows, keys, missing)
+
+	for i, row := range rows {
+		results[i] = row.result
+	}
+}
+
+func sortRows(rows []sortRow, keys []SortKey, missing MissingOrder) {
+	sort.SliceStable(rows, func(i, j int) bool {
+		return compareSortValues(rows[i].values, rows[j].values, keys, missing) < 0
+	})
+}
+
+type sortRow struct {
+	result KeyValue
+	values []sortValue
+}
+
+func newSortRow(result KeyValue, keys []SortKey) sortRow {
+	row := sortRow{result, make([]sortValue, len(keys))}
+	for i, key := range keys {
+		row.values[i] = sortValueOf(result, key.Key)
+	}
+	return row
+}
+
+// compareSortValues compares the values of two rows by the keys.
+func compareSortValues(a, b []sortValue, keys []SortKey, missing MissingOrder) int {
+	for n, key := range keys {
+		x, y := a[n], b[n]
+		if x.missing() || y.missing() {
+			if x.missing() == y.missing() {
+				continue
+			}
+			if x.missing() == (missing == MissingFirst) {
+				return -1
+			}
+			return 1
+		}
+		c := x.compare(y)
+		if key.Descending {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// sortValue is a value with the rank of its type.
+type sortValue struct {
+	rank   int // 0: missing, 1: boolean, 2: number, 3: string
+	b      bool
+	number *big.Rat
+	s      string
+}
+
+func sortValueOf(result KeyValue, key string) sortValue {
+	value, ok := result[key]
+	if !ok {
+		return sortValue{}
+	}
+	switch jsonTypeOf(value) {
+	case "boolean":
+		return sortValue{rank: 1, b: value.(bool)}
+	case "number":
+		if r, ok := new(big.Rat).SetString(toNumber(value).String()); ok {
+			return sortValue{rank: 2, number: r}
+		}
+		return sortValue{} // NaN and Inf
+	case "string":
+		return sortValue{rank: 3, s: toString(value)}
+	default:
+		return sortValue{}
+	}
+}
+
+func (v sortValue) missing() bool {
+	return v.rank == 0
+}
+
+func (v sortValue) compare(o sortValue) int {
+	if v.rank != o.rank {
+		return v.rank - o.rank
+	}
+	switch v.rank {
+	case 1:
+		if v.b == o.b {
+			return 0
+		} else if o.b {
+			return -1
+		}
+		return 1
+	case 2:
+		return v.number.Cmp(o.number)
+	default:
+		return strings.Compare(v.s, o.s)
+	}
+}
@@ -0,0 +1,60 @@
+package json2csv
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSortResults(t *testing.T) {
+	obj, err := json2obj(`[
+		{"id": 1, "last": "Smith", "created": 20},
+		{"id": 2, "last": "Doe", "created": 3},
+		{"id": 3, "created": 100},
+		{"id": 4, "last": "Smith", "created": 100.5},
+		{"id": 5, "last": null, "created": 1e2},
+		{"id": 6, "last": "Doe", "created": 3.0},
+		{"id": 7, "last": true},
+		{"id": 8, "last": 10}
+	]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		keys     []SortKey
+		missing  MissingOrder
+		expected []int
+	}{
+		{nil, MissingLast, []int{1, 2, 3, 4, 5, 6, 7, 8}},
+		{[]SortKey{{"/last", false}}, MissingLast, []int{7, 8, 2, 6, 1, 4, 3, 5}},
+		{[]SortKey{{"/last", false}}, MissingFirst, []int{3, 5, 7, 8, 2, 6, 1, 4}},
+		{[]SortKey{{"/last", true}}, MissingLast, []int{1, 4, 2, 6, 8, 7, 3, 5}},
+		{[]SortKey{{"/last", false}, {"/created", true}}, MissingLast, []int{7, 8, 2, 6, 4, 1, 3, 5}},
+		{[]SortKey{{"/created", false}}, MissingLast, []int{2, 6, 1, 3, 5, 4, 7, 8}},
+		{[]SortKey{{"/created", true}, {"/id", true}}, MissingFirst, []int{8, 7, 4, 5, 3, 1, 6, 2}},
+	}
+
+	for caseIndex, testCase := range testCases {
+		results, err := JSON2CSV(obj)
+		if err != nil {
+			t.Fatal(err)
+		}
+		SortResults(results, testCase.keys, testCase.missing)
+
+		actual := make([]int, 0, len(results))
+		for _, result := range results {
+			id, _ := result["/id"].(json.Number).Int64()
+			actual = append(actual, int(id))
+		}
+		if len(actual) != len(testCase.expected) {
+			t.Errorf("%d: Expected %v, but %v", caseIndex, testCase.expected, actual)
+			continue
+		}
+		for i := range actual {
+			if actual[i] != testCase.expected[i] {
+				t.Errorf("%d: Expected %v, but %v", caseIndex, testCase.expected, actual)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package json2csv
+
+import (
+	"bufio"
+	"container/heap"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+)
+
+// spilledRuns sorts items holding at most size of them in memory. Larger
+// inputs are sorted in runs of size items, which are written to temporary
+// files and merged.
+type spilledRuns struct {
+	size   int // zero or negative means no limit
+	dir    string
+	prefix string
+
+	// compare returns a negative number, zero or a positive number if a is
+	// less than, equal to or greater than b.
+	compare func(a, b interface{}) int
+
+	// encoder and decoder return functions that write and read the items of
+	// a temporary file. decode returns io.EOF at the end.
+	encoder func(w io.Writer) func(item interface{}) error
+	decoder func(r io.Reader) func() (interface{}, error)
+
+	items []interface{}
+	files []*os.File
+}
+
+// add adds the item. It writes the items held in memory to a temporary file
+// when they reach the size.
+func (s *spilledRuns) add(item interface{}) error {
+	s.items = append(s.items, item)
+	if s.size > 0 && len(s.items) >= s.size {
+		return s.spill()
+	}
+	return nil
+}
+
+// spill sorts the items held in memory and writes them to a temporary file.
+func (s *spilledRuns) spill() error {
+	s.sort()
+
+	f, err := ioutil.TempFile(s.dir, s.prefix)
+	if err != nil {
+		return err
+	}
+	s.files = append(s.files, f)
+
+	w := bufio.NewWriter(f)
+	encode := s.encoder(w)
+	for _, item := range s.items {
+		if err := encode(item); err != nil {
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	s.items = s.items[:0]
+	return nil
+}
+
+func (s *spilledRuns) sort() {
+	sort.SliceStable(s.items, func(i, j int) bool {
+		return s.compare(s.items[i], s.items[j]) < 0
+	})
+}
+
+// each calls fn for each item in the sorted order, merging the temporary
+// files and the items held in memory. The order of equal items is kept.
+// It can be called only once.
+func (s *spilledRuns) each(fn func(interface{}) error) error {
+	s.sort()
+
+	runs := make([]sortedRun, 0, len(s.files)+1)
+	for _, f := range s.files {
+		runs = append(runs, s.decoder(bufio.NewReader(f)))
+	}
+	rest := s.items
+	runs = append(runs, func() (interface{}, error) {
+		if len(rest) == 0 {
+			return nil, io.EOF
+		}
+		item := rest[0]
+		rest = rest[1:]
+		return item, nil
+	})
+	return mergeRuns(runs, s.compare, fn)
+}
+
+// close removes the temporary files.
+func (s *spilledRuns) close() error {
+	var err error
+	for _, f := range s.files {
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if removeErr := os.Remove(f.Name()); err == nil {
+			err = removeErr
+		}
+	}
+	s.files = nil
+	s.items = nil
+	return err
+}
+
+// sortedRun returns the items of a sorted run one by one, and io.EOF at the
+// end.
+type sortedRun func() (interface{}, error)
+
+// mergeRuns calls fn with the items of the sorted runs in the order of
+// compare. Equal items are passed in the order of the runs, so that the merge
+// is stable if the runs are in the input order.
+func mergeRuns(runs []sortedRun, compare func(a, b interface{}) int, fn func(interface{}) error) error {
+	h := &runHeap{compare: compare}
+	for i, next := range runs {
+		item, err := next()
+		if err == io.EOF {
+			continue
+		} else if err != nil {
+			return err
+		}
+		heap.Push(h, &runHead{i, item, next})
+	}
+
+	for h.Len() > 0 {
+		top := h.heads[0]
+		if err := fn(top.item); err != nil {
+			return err
+		}
+		item, err := top.next()
+		if err == io.EOF {
+			heap.Pop(h)
+			continue
+		} else if err != nil {
+			return err
+		}
+		top.item = item
+		heap.Fix(h, 0)
+	}
+	return nil
+}
+
+// runHead is the current item of a run being merged.
+type runHead struct {
+	index int
+	item  interface{}
+	next  sortedRun
+}
+
+// runHeap is a heap of runs ordered by their current items.
+type runHeap struct {
+	compare func(a, b interface{}) int
+	heads   []*runHead
+}
+
+func (h *runHeap) Len() int      { return len(h.heads) }
+func (h *runHeap) Swap(i, j int) { h.heads[i], h.heads[j] = h.heads[j], h.heads[i] }
+func (h *runHeap) Less(i, j int) bool {
+	a, b := h.heads[i], h.heads[j]
+	if c := h.compare(a.item, b.item); c != 0 {
+		return c < 0
+	}
+	return a.index < b.index
+}
+func (h *runHeap) Push(x interface{}) { h.heads = append(h.heads, x.(*runHead)) }
+func (h *runHeap) Pop() interface{} {
+	r := h.heads[len(h.heads)-1]
+	h.heads = h.heads[:len(h.heads)-1]
+	return r
+}
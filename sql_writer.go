@@ -0,0 +1,227 @@
+package json2csv
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// SQLDialect represents the dialect of SQL statements.
+type SQLDialect uint
+
+// SQL dialects
+const (
+	PostgreSQLDialect SQLDialect = iota
+	MySQLDialect
+	SQLiteDialect
+)
+
+// DefaultSQLBatchSize is the default number of rows per INSERT statement.
+const DefaultSQLBatchSize = 100
+
+// SQLWriter writes CREATE TABLE and INSERT statements.
+type SQLWriter struct {
+	w           io.Writer
+	Table       string
+	Dialect     SQLDialect
+	HeaderStyle KeyStyle
+	BatchSize   int
+}
+
+// NewSQLWriter returns new SQLWriter with PostgreSQLDialect and JSONPointerStyle.
+func NewSQLWriter(w io.Writer, table string) *SQLWriter {
+	return &SQLWriter{
+		w,
+		table,
+		PostgreSQLDialect,
+		JSONPointerStyle,
+		DefaultSQLBatchSize,
+	}
+}
+
+// WriteSQL writes a CREATE TABLE statement followed by INSERT statements.
+func (w *SQLWriter) WriteSQL(results []KeyValue) error {
+	if w.Table == "" {
+		return errors.New("Table name is required")
+	}
+
+	pts, err := sortedPointers(results)
+	if err != nil {
+		return err
+	}
+	keys := pts.Strings()
+	if len(keys) == 0 {
+		return errors.New("No columns to write")
+	}
+	columns := uniqueColumnNames(headerOf(pts, w.HeaderStyle))
+	types := InferColumnTypes(results, keys)
+
+	bw := bufio.NewWriter(w.w)
+	bw.WriteString(createTableSQL(w.Dialect, w.Table, columns, types) + ";\n")
+
+	batchSize := w.BatchSize
+	if batchSize <= 0 {
+		batchSize = len(results)
+	}
+	for start := 0; start < len(results); start += batchSize {
+		end := start + batchSize
+		if end > len(results) {
+			end = len(results)
+		}
+		w.writeInsert(bw, results[start:end], keys, columns, types)
+	}
+
+	return bw.Flush()
+}
+
+func createTableSQL(d SQLDialect, table string, columns []string, types []ColumnType) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "CREATE TABLE %s (\n", d.QuoteIdentifier(table))
+	for i, column := range columns {
+		fmt.Fprintf(&b, "  %s %s", d.QuoteIdentifier(column), d.TypeName(types[i]))
+		if i < len(columns)-1 {
+			b.WriteString(",")
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString(")")
+	return b.String()
+}
+
+func (w *SQLWriter) writeInsert(bw *bufio.Writer, results []KeyValue, keys, columns []string, types []ColumnType) {
+	quoted := make([]string, 0, len(columns))
+	for _, column := range columns {
+		quoted = append(quoted, w.Dialect.QuoteIdentifier(column))
+	}
+	fmt.Fprintf(bw, "INSERT INTO %s (%s) VALUES\n", w.Dialect.QuoteIdentifier(w.Table), strings.Join(quoted, ", "))
+
+	values := make([]string, len(keys))
+	for i, result := range results {
+		for j, key := range keys {
+			values[j] = w.Dialect.Literal(result[key], types[j])
+		}
+		fmt.Fprintf(bw, "  (%s)", strings.Join(values, ", "))
+		if i < len(results)-1 {
+			bw.WriteString(",\n")
+		} else {
+			bw.WriteString(";\n")
+		}
+	}
+}
+
+// QuoteIdentifier returns the quoted identifier.
+func (d SQLDialect) QuoteIdentifier(name string) string {
+	if d == MySQLDialect {
+		return "`" + strings.Replace(name, "`", "``", -1) + "`"
+	}
+	return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
+
+// TypeName returns the SQL type name for the column type.
+func (d SQLDialect) TypeName(t ColumnType) string {
+	switch d {
+	case SQLiteDialect:
+		switch t {
+		case BooleanColumn, IntegerColumn:
+			return "INTEGER"
+		case FloatColumn:
+			return "REAL"
+		default:
+			// Integers out of the range of int64 are stored as text, as
+			// SQLite would convert them to REAL.
+			return "TEXT"
+		}
+	case MySQLDialect:
+		switch t {
+		case BooleanColumn:
+			return "BOOLEAN"
+		case IntegerColumn:
+			return "BIGINT"
+		case BigIntegerColumn:
+			return "DECIMAL(65,0)"
+		case FloatColumn:
+			return "DOUBLE"
+		default:
+			return "TEXT"
+		}
+	default:
+		switch t {
+		case BooleanColumn:
+			return "BOOLEAN"
+		case IntegerColumn:
+			return "BIGINT"
+		case BigIntegerColumn:
+			return "NUMERIC"
+		case FloatColumn:
+			return "DOUBLE PRECISION"
+		default:
+			return "TEXT"
+		}
+	}
+}
+
+// Literal returns the SQL literal of the value for the column type.
+// Missing and null values are represented as NULL.
+func (d SQLDialect) Literal(value interface{}, t ColumnType) string {
+	if value == nil {
+		return "NULL"
+	}
+
+	switch t {
+	case BooleanColumn:
+		if b, ok := value.(bool); ok {
+			if d == SQLiteDialect {
+				if b {
+					return "1"
+				}
+				return "0"
+			}
+			return strings.ToUpper(strconv.FormatBool(b))
+		}
+	case BigIntegerColumn:
+		if d != SQLiteDialect {
+			return toString(value)
+		}
+	case IntegerColumn, FloatColumn:
+		switch v := value.(type) {
+		case json.Number:
+			return v.String()
+		case int64, uint64:
+			return toString(v)
+		case float64:
+			return strconv.FormatFloat(v, 'g', -1, 64)
+		}
+	}
+	return d.quoteString(toString(value))
+}
+
+func (d SQLDialect) quoteString(s string) string {
+	if d == MySQLDialect {
+		// MySQL treats backslashes as escape characters by default.
+		s = strings.Replace(s, `\`, `\\`, -1)
+	}
+	return "'" + strings.Replace(s, "'", "''", -1) + "'"
+}
+
+// uniqueColumnNames returns names that are not empty and not duplicated
+// case-insensitively, by appending "_2", "_3", ... to later duplicates.
+func uniqueColumnNames(names []string) []string {
+	used := make(map[string]bool, len(names))
+	unique := make([]string, 0, len(names))
+	for _, name := range names {
+		if name == "" {
+			name = "column"
+		}
+		candidate := name
+		for n := 2; used[strings.ToLower(candidate)]; n++ {
+			candidate = fmt.Sprintf("%s_%d", name, n)
+		}
+		used[strings.ToLower(candidate)] = true
+		unique = append(unique, candidate)
+	}
+	return unique
+}
@@ -0,0 +1,190 @@
+package json2csv
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"reflect"
+	"testing"
+)
+
+var testInferColumnTypesCases = []struct {
+	values   []interface{}
+	expected ColumnType
+}{
+	{[]interface{}{json.Number("1"), json.Number("2")}, IntegerColumn},
+	{[]interface{}{json.Number("1"), json.Number("2.5")}, FloatColumn},
+	{[]interface{}{json.Number("1e3")}, FloatColumn},
+	{[]interface{}{int64(1), uint64(2)}, IntegerColumn},
+	{[]interface{}{int64(1), uint64(math.MaxUint64)}, BigIntegerColumn},
+	{[]interface{}{json.Number("1"), json.Number("9223372036854775808")}, BigIntegerColumn},
+	{[]interface{}{json.Number("-9223372036854775809")}, BigIntegerColumn},
+	{[]interface{}{json.Number("9223372036854775808"), 1.5}, FloatColumn},
+	{[]interface{}{1.5}, FloatColumn},
+	{[]interface{}{true, false}, BooleanColumn},
+	{[]interface{}{true, json.Number("1")}, TextColumn},
+	{[]interface{}{"foo", json.Number("1")}, TextColumn},
+	{[]interface{}{nil, json.Number("1")}, IntegerColumn},
+	{[]interface{}{nil}, UnknownColumn},
+}
+
+func TestInferColumnTypes(t *testing.T) {
+	for caseIndex, testCase := range testInferColumnTypesCases {
+		results := []KeyValue{}
+		for _, value := range testCase.values {
+			results = append(results, KeyValue{"/a": value})
+		}
+		results = append(results, KeyValue{})
+
+		actual := InferColumnTypes(results, []string{"/a"})
+		if !reflect.DeepEqual(actual, []ColumnType{testCase.expected}) {
+			t.Errorf("%d: Expected %v, but %v", caseIndex, testCase.expected, actual)
+		}
+	}
+}
+
+var testQuoteIdentifierCases = []struct {
+	dialect  SQLDialect
+	name     string
+	expected string
+}{
+	{PostgreSQLDialect, `foo`, `"foo"`},
+	{PostgreSQLDialect, `fo"o`, `"fo""o"`},
+	{SQLiteDialect, `/foo/0`, `"/foo/0"`},
+	{MySQLDialect, "foo", "`foo`"},
+	{MySQLDialect, "fo`o", "`fo``o`"},
+}
+
+func TestQuoteIdentifier(t *testing.T) {
+	for caseIndex, testCase := range testQuoteIdentifierCases {
+		actual := testCase.dialect.QuoteIdentifier(testCase.name)
+		if actual != testCase.expected {
+			t.Errorf("%d: Expected %v, but %v", caseIndex, testCase.expected, actual)
+		}
+	}
+}
+
+var testLiteralCases = []struct {
+	dialect  SQLDialect
+	value    interface{}
+	typ      ColumnType
+	expected string
+}{
+	{PostgreSQLDialect, nil, IntegerColumn, `NULL`},
+	{PostgreSQLDialect, json.Number("12"), IntegerColumn, `12`},
+	{PostgreSQLDialect, json.Number("12"), TextColumn, `'12'`},
+	{PostgreSQLDialect, 1000000.0, FloatColumn, `1e+06`},
+	{PostgreSQLDialect, uint64(math.MaxUint64), BigIntegerColumn, `18446744073709551615`},
+	{MySQLDialect, json.Number("99999999999999999999"), BigIntegerColumn, `99999999999999999999`},
+	{SQLiteDialect, uint64(math.MaxUint64), BigIntegerColumn, `'18446744073709551615'`},
+	{PostgreSQLDialect, true, BooleanColumn, `TRUE`},
+	{SQLiteDialect, true, BooleanColumn, `1`},
+	{SQLiteDialect, false, BooleanColumn, `0`},
+	{PostgreSQLDialect, `it's`, TextColumn, `'it''s'`},
+	{PostgreSQLDialect, `a\b`, TextColumn, `'a\b'`},
+	{MySQLDialect, `a\b`, TextColumn, `'a\\b'`},
+}
+
+func TestLiteral(t *testing.T) {
+	for caseIndex, testCase := range testLiteralCases {
+		actual := testCase.dialect.Literal(testCase.value, testCase.typ)
+		if actual != testCase.expected {
+			t.Errorf("%d: Expected %v, but %v", caseIndex, testCase.expected, actual)
+		}
+	}
+}
+
+func TestUniqueColumnNames(t *testing.T) {
+	actual := uniqueColumnNames([]string{"a.b", "A.B", "a.b", "", "a.b_2"})
+	expected := []string{"a.b", "A.B_2", "a.b_3", "column", "a.b_2_2"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected %v, but %v", expected, actual)
+	}
+}
+
+func TestWriteSQL(t *testing.T) {
+	results := []KeyValue{
+		{"/id": json.Number("1"), "/name": "foo", "/score": json.Number("1.5"), "/active": true},
+		{"/id": json.Number("2"), "/name": "it's", "/active": nil},
+		{"/id": json.Number("3")},
+	}
+
+	b := &bytes.Buffer{}
+	w := NewSQLWriter(b, "users")
+	w.HeaderStyle = DotNotationStyle
+	w.BatchSize = 2
+	if err := w.WriteSQL(results); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `CREATE TABLE "users" (
+  "active" BOOLEAN,
+  "id" BIGINT,
+  "name" TEXT,
+  "score" DOUBLE PRECISION
+);
+INSERT INTO "users" ("active", "id", "name", "score") VALUES
+  (TRUE, 1, 'foo', 1.5),
+  (NULL, 2, 'it''s', NULL);
+INSERT INTO "users" ("active", "id", "name", "score") VALUES
+  (NULL, 3, NULL, NULL);
+`
+	if actual := b.String(); actual != expected {
+		t.Errorf("Expected %v, but %v", expected, actual)
+	}
+}
+
+func TestWriteSQLNoColumns(t *testing.T) {
+	w := NewSQLWriter(&bytes.Buffer{}, "users")
+	if err := w.WriteSQL([]KeyValue{{}}); err == nil {
+		t.Error("Expected error")
+	}
+}
+
+func TestWriteSQLBigIntegers(t *testing.T) {
+	results := []KeyValue{
+		{"/n": json.Number("1"), "/u": uint64(math.MaxUint64)},
+		{"/n": json.Number("123456789012345678901234567890")},
+	}
+
+	var testCases = []struct {
+		dialect  SQLDialect
+		expected string
+	}{
+		{PostgreSQLDialect, `CREATE TABLE "t" (
+  "n" NUMERIC,
+  "u" NUMERIC
+);
+INSERT INTO "t" ("n", "u") VALUES
+  (1, 18446744073709551615),
+  (123456789012345678901234567890, NULL);
+`},
+		{MySQLDialect, "CREATE TABLE `t` (\n" +
+			"  `n` DECIMAL(65,0),\n" +
+			"  `u` DECIMAL(65,0)\n" +
+			");\n" +
+			"INSERT INTO `t` (`n`, `u`) VALUES\n" +
+			"  (1, 18446744073709551615),\n" +
+			"  (123456789012345678901234567890, NULL);\n"},
+		{SQLiteDialect, `CREATE TABLE "t" (
+  "n" TEXT,
+  "u" TEXT
+);
+INSERT INTO "t" ("n", "u") VALUES
+  ('1', '18446744073709551615'),
+  ('123456789012345678901234567890', NULL);
+`},
+	}
+	for caseIndex, testCase := range testCases {
+		b := &bytes.Buffer{}
+		w := NewSQLWriter(b, "t")
+		w.HeaderStyle = DotNotationStyle
+		w.Dialect = testCase.dialect
+		if err := w.WriteSQL(results); err != nil {
+			t.Fatal(err)
+		}
+		if actual := b.String(); actual != testCase.expected {
+			t.Errorf("%d: Expected %v, but %v", caseIndex, testCase.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package json2csv
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// SQLiteWriter writes records into a table of a SQLite database.
+//
+// The table is created if it does not exist. If it exists, columns that
+// are not in the table yet are added by ALTER TABLE.
+type SQLiteWriter struct {
+	db          *sql.DB
+	Table       string
+	HeaderStyle KeyStyle
+}
+
+// NewSQLiteWriter returns new SQLiteWriter with JSONPointerStyle.
+// The db must be opened with a SQLite driver.
+func NewSQLiteWriter(db *sql.DB, table string) *SQLiteWriter {
+	return &SQLiteWriter{
+		db,
+		table,
+		JSONPointerStyle,
+	}
+}
+
+// WriteSQLite inserts records into the table in a transaction.
+func (w *SQLiteWriter) WriteSQLite(results []KeyValue) (err error) {
+	if w.Table == "" {
+		return errors.New("Table name is required")
+	}
+
+	pts, err := sortedPointers(results)
+	if err != nil {
+		return err
+	}
+	keys := pts.Strings()
+	if len(keys) == 0 {
+		return errors.New("No columns to write")
+	}
+	columns := uniqueColumnNames(headerOf(pts, w.HeaderStyle))
+	types := InferColumnTypes(results, keys)
+
+	tx, err := w.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err = w.prepareTable(tx, columns, types); err != nil {
+		return err
+	}
+
+	stmt, err := tx.Prepare(insertSQL(SQLiteDialect, w.Table, columns))
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	args := make([]interface{}, len(keys))
+	for _, result := range results {
+		for i, key := range keys {
+			args[i] = sqliteValue(result[key], types[i])
+		}
+		if _, err = stmt.Exec(args...); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (w *SQLiteWriter) prepareTable(tx *sql.Tx, columns []string, types []ColumnType) error {
+	existing, err := sqliteTableColumns(tx, w.Table)
+	if err != nil {
+		return err
+	}
+
+	if existing == nil {
+		_, err := tx.Exec(createTableSQL(SQLiteDialect, w.Table, columns, types))
+		return err
+	}
+
+	for i, column := range columns {
+		if existing[strings.ToLower(column)] {
+			continue
+		}
+		query := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s",
+			SQLiteDialect.QuoteIdentifier(w.Table),
+			SQLiteDialect.QuoteIdentifier(column),
+			SQLiteDialect.TypeName(types[i]))
+		if _, err := tx.Exec(query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sqliteTableColumns returns the set of lower-cased column names of the table,
+// or nil if the table does not exist.
+func sqliteTableColumns(tx *sql.Tx, table string) (map[string]bool, error) {
+	rows, err := tx.Query(fmt.Sprintf("PRAGMA table_info(%s)", SQLiteDialect.QuoteIdentifier(table)))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var columns map[string]bool
+	for rows.Next() {
+		var (
+			cid       int
+			name      string
+			typ       string
+			notNull   bool
+			dfltValue interface{}
+			pk        int
+		)
+		if err := rows.Scan(&cid, &name, &typ, &notNull, &dfltValue, &pk); err != nil {
+			return nil, err
+		}
+		if columns == nil {
+			columns = make(map[string]bool)
+		}
+		columns[strings.ToLower(name)] = true
+	}
+	return columns, rows.Err()
+}
+
+func insertSQL(d SQLDialect, table string, columns []string) string {
+	quoted := make([]string, 0, len(columns))
+	placeholders := make([]string, 0, len(columns))
+	for _, column := range columns {
+		quoted = append(quoted, d.QuoteIdentifier(column))
+		placeholders = append(placeholders, "?")
+	}
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+		d.QuoteIdentifier(table), strings.Join(quoted, ", "), strings.Join(placeholders, ", "))
+}
+
+func sqliteValue(value interface{}, t ColumnType) interface{} {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case bool:
+		if t == BooleanColumn {
+			if v {
+				return int64(1)
+			}
+			return int64(0)
+		}
+	case json.Number:
+		switch t {
+		case IntegerColumn:
+			if i, err := v.Int64(); err == nil {
+				return i
+			}
+		case FloatColumn:
+			if f, err := v.Float64(); err == nil {
+				return f
+			}
+		}
+	case int64, float64:
+		if t == IntegerColumn || t == FloatColumn {
+			return v
+		}
+	case uint64:
+		if t == IntegerColumn || t == FloatColumn {
+			if v <= math.MaxInt64 {
+				return int64(v)
+			}
+			return float64(v)
+		}
+	}
+	return toString(value)
+}
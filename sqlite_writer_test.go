@@ -0,0 +1,65 @@
+package json2csv
+
+import (
+	"database/sql"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	_ "modernc.org/sqlite"
+)
+
+func TestWriteSQLite(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	w := NewSQLiteWriter(db, "users")
+	w.HeaderStyle = DotNotationStyle
+
+	first := []KeyValue{
+		{"/id": json.Number("1"), "/name": "foo", "/active": true},
+		{"/id": json.Number("2"), "/name": nil},
+	}
+	if err := w.WriteSQLite(first); err != nil {
+		t.Fatal(err)
+	}
+
+	second := []KeyValue{
+		{"/id": json.Number("3"), "/profile/score": json.Number("1.5")},
+	}
+	if err := w.WriteSQLite(second); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := db.Query(`SELECT "id", "name", "active", "profile.score" FROM "users" ORDER BY "id"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	var actual [][]interface{}
+	for rows.Next() {
+		var id int64
+		var name, active, score interface{}
+		if err := rows.Scan(&id, &name, &active, &score); err != nil {
+			t.Fatal(err)
+		}
+		actual = append(actual, []interface{}{id, name, active, score})
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := [][]interface{}{
+		{int64(1), "foo", int64(1), nil},
+		{int64(2), nil, nil, nil},
+		{int64(3), nil, nil, 1.5},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected %v, but %v", expected, actual)
+	}
+}
@@ -0,0 +1,187 @@
+package json2csv
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"time"
+)
+
+// TimeKind represents how timestamps are stored in values.
+type TimeKind uint
+
+// Time kinds
+const (
+	// RFC 3339 strings, or Unix epoch numbers in the unit guessed from the
+	// magnitude.
+	AutoTime TimeKind = iota
+
+	// RFC 3339 strings. (e.g. "2006-01-02T15:04:05Z07:00")
+	RFC3339Time
+
+	// Unix epoch seconds, milliseconds, microseconds and nanoseconds.
+	// Numeric strings are also accepted.
+	EpochSeconds
+	EpochMillis
+	EpochMicros
+	EpochNanos
+)
+
+// nanoseconds per unit of each epoch kind
+var epochUnits = map[TimeKind]int64{
+	EpochSeconds: int64(time.Second),
+	EpochMillis:  int64(time.Millisecond),
+	EpochMicros:  int64(time.Microsecond),
+	EpochNanos:   1,
+}
+
+// TimeRule parses values as timestamps and formats them in the layout and
+// the location.
+type TimeRule struct {
+	Kind TimeKind
+
+	// Layout is the layout of time.Format. Default is time.RFC3339.
+	Layout string
+
+	// Location converts timestamps into the time zone. If nil, epoch
+	// timestamps are in UTC and RFC 3339 timestamps keep their offsets.
+	Location *time.Location
+}
+
+// TimeError is reported when the value cannot be parsed as a timestamp.
+type TimeError struct {
+	Key   string
+	Value interface{}
+}
+
+func (e *TimeError) Error() string {
+	return fmt.Sprintf("%s: invalid time %q", e.Key, toString(e.Value))
+}
+
+var errInvalidTime = errors.New("invalid time")
+
+// Parse parses the value as a timestamp.
+func (r *TimeRule) Parse(value interface{}) (time.Time, error) {
+	var t time.Time
+	var err error
+	switch r.Kind {
+	case RFC3339Time:
+		t, err = parseRFC3339(value)
+	case AutoTime:
+		if n, ok := epochNumber(value); ok {
+			t, err = epochTime(n, epochKindOf(n))
+		} else {
+			t, err = parseRFC3339(value)
+		}
+	default:
+		n, ok := epochNumber(value)
+		if !ok {
+			return time.Time{}, errInvalidTime
+		}
+		t, err = epochTime(n, r.Kind)
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	if r.Location != nil {
+		t = t.In(r.Location)
+	}
+	return t, nil
+}
+
+// Format parses the value and formats it in the layout.
+func (r *TimeRule) Format(value interface{}) (string, error) {
+	t, err := r.Parse(value)
+	if err != nil {
+		return "", err
+	}
+	layout := r.Layout
+	if layout == "" {
+		layout = time.RFC3339
+	}
+	return t.Format(layout), nil
+}
+
+// TimeFormatters returns CellFormatters of the rules keyed by the keys (JSON
+// Pointers). Null values are written as empty cells. Invalid values are
+// written as they are, and *TimeError is reported to warn if it is not nil.
+func TimeFormatters(rules map[string]*TimeRule, warn func(error)) map[string]CellFormatter {
+	formatters := make(map[string]CellFormatter, len(rules))
+	for key, rule := range rules {
+		key, rule := key, rule
+		formatters[key] = func(value interface{}) string {
+			if value == nil {
+				return ""
+			}
+			s, err := rule.Format(value)
+			if err != nil {
+				if warn != nil {
+					warn(&TimeError{key, value})
+				}
+				return toString(value)
+			}
+			return s
+		}
+	}
+	return formatters
+}
+
+func parseRFC3339(value interface{}) (time.Time, error) {
+	s, ok := value.(string)
+	if !ok {
+		return time.Time{}, errInvalidTime
+	}
+	return time.Parse(time.RFC3339Nano, s)
+}
+
+// epochNumber returns the value as an exact number.
+func epochNumber(value interface{}) (*big.Rat, bool) {
+	var s string
+	switch v := value.(type) {
+	case json.Number:
+		s = v.String()
+	case string:
+		s = v
+	case int64:
+		s = strconv.FormatInt(v, 10)
+	case uint64:
+		s = strconv.FormatUint(v, 10)
+	case float64:
+		r := new(big.Rat).SetFloat64(v) // nil if NaN or Inf
+		return r, r != nil
+	default:
+		return nil, false
+	}
+	if _, err := strconv.ParseFloat(s, 64); err != nil {
+		return nil, false
+	}
+	return new(big.Rat).SetString(s)
+}
+
+// epochKindOf guesses the unit from the magnitude. Seconds cover until the
+// year 5138, so larger numbers are treated as the smaller unit.
+func epochKindOf(n *big.Rat) TimeKind {
+	abs := new(big.Rat).Abs(n)
+	switch {
+	case abs.Cmp(big.NewRat(1e11, 1)) < 0:
+		return EpochSeconds
+	case abs.Cmp(big.NewRat(1e14, 1)) < 0:
+		return EpochMillis
+	case abs.Cmp(big.NewRat(1e17, 1)) < 0:
+		return EpochMicros
+	default:
+		return EpochNanos
+	}
+}
+
+func epochTime(n *big.Rat, kind TimeKind) (time.Time, error) {
+	ns := new(big.Rat).Mul(n, big.NewRat(epochUnits[kind], 1))
+	i := new(big.Int).Quo(ns.Num(), ns.Denom())
+	sec, nsec := new(big.Int).DivMod(i, big.NewInt(int64(time.Second)), new(big.Int))
+	if !sec.IsInt64() {
+		return time.Time{}, errInvalidTime
+	}
+	return time.Unix(sec.Int64(), nsec.Int64()).UTC(), nil
+}
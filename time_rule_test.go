@@ -0,0 +1,97 @@
+package json2csv
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+)
+
+var testTimeRuleCases = []struct {
+	kind     TimeKind
+	layout   string
+	location string
+	value    interface{}
+	expected string
+}{
+	{RFC3339Time, "", "", "2024-01-02T03:04:05+09:00", "2024-01-02T03:04:05+09:00"},
+	{RFC3339Time, "2006-01-02 15:04:05", "UTC", "2024-01-02T03:04:05+09:00", "2024-01-01 18:04:05"},
+	{EpochSeconds, "", "", json.Number("1700000000"), "2023-11-14T22:13:20Z"},
+	{EpochSeconds, time.RFC3339Nano, "", json.Number("1700000000.25"), "2023-11-14T22:13:20.25Z"},
+	{EpochMillis, "2006-01-02 15:04:05.000", "Asia/Tokyo", json.Number("1700000000123"), "2023-11-15 07:13:20.123"},
+	{EpochMillis, "", "", "1700000000123", "2023-11-14T22:13:20Z"},
+	{EpochMicros, "", "", int64(1700000000000000), "2023-11-14T22:13:20Z"},
+	{EpochNanos, "", "", json.Number("-1"), "1969-12-31T23:59:59Z"},
+	{AutoTime, "", "", json.Number("1700000000"), "2023-11-14T22:13:20Z"},
+	{AutoTime, "", "", 1700000000123.0, "2023-11-14T22:13:20Z"},
+	{AutoTime, "", "", "2024-01-02T03:04:05Z", "2024-01-02T03:04:05Z"},
+	{AutoTime, "", "", "1700000000000000000", "2023-11-14T22:13:20Z"},
+}
+
+func TestTimeRuleFormat(t *testing.T) {
+	for caseIndex, testCase := range testTimeRuleCases {
+		rule := &TimeRule{Kind: testCase.kind, Layout: testCase.layout}
+		if testCase.location != "" {
+			loc, err := time.LoadLocation(testCase.location)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rule.Location = loc
+		}
+		actual, err := rule.Format(testCase.value)
+		if err != nil {
+			t.Errorf("%d: %s", caseIndex, err)
+		} else if actual != testCase.expected {
+			t.Errorf("%d: Expected %v, but %v", caseIndex, testCase.expected, actual)
+		}
+	}
+}
+
+func TestTimeRuleFormatInvalid(t *testing.T) {
+	values := []struct {
+		kind  TimeKind
+		value interface{}
+	}{
+		{RFC3339Time, json.Number("1700000000")},
+		{RFC3339Time, "2024-01-02"},
+		{EpochSeconds, "yesterday"},
+		{EpochSeconds, true},
+		{AutoTime, "NaN"},
+		{AutoTime, math.NaN()},
+	}
+	for caseIndex, testCase := range values {
+		rule := &TimeRule{Kind: testCase.kind}
+		if _, err := rule.Format(testCase.value); err == nil {
+			t.Errorf("%d: Expected error, but nil", caseIndex)
+		}
+	}
+}
+
+func TestWriteCSVTimeFormatters(t *testing.T) {
+	results := []KeyValue{
+		{"/id": json.Number("1"), "/at": json.Number("1700000000")},
+		{"/id": json.Number("2"), "/at": "soon"},
+		{"/id": json.Number("3"), "/at": nil},
+	}
+
+	var warnings []string
+	b := &bytes.Buffer{}
+	w := NewCSVWriter(b)
+	w.Formatters = TimeFormatters(map[string]*TimeRule{
+		"/at": {Kind: EpochSeconds, Layout: "2006-01-02"},
+	}, func(err error) {
+		warnings = append(warnings, err.Error())
+	})
+	if err := w.WriteCSV(results); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "/at,/id\n2023-11-14,1\nsoon,2\n,3\n"
+	if b.String() != expected {
+		t.Errorf("Expected %q, but %q", expected, b.String())
+	}
+	if len(warnings) != 1 || warnings[0] != `/at: invalid time "soon"` {
+		t.Errorf("Unexpected warnings %v", warnings)
+	}
+}
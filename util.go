@@ -18,5 +18,8 @@ func valueOf(obj interface{}) reflect.Value {
 }
 
 func toString(obj interface{}) string {
+	if obj == nil {
+		return ""
+	}
 	return fmt.Sprintf("%v", obj)
 }
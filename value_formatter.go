@@ -0,0 +1,122 @@
+package json2csv
+
+import (
+	"encoding/json"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// ValueFormatter converts values to cell strings.
+type ValueFormatter interface {
+	FormatValue(value interface{}) string
+}
+
+// StandardValueFormatter is the default ValueFormatter of CSVWriter.
+// Numbers are never written in exponent notation.
+type StandardValueFormatter struct {
+	// TrueLiteral and FalseLiteral are cells of booleans.
+	// (e.g. "1" and "0", "TRUE" and "FALSE", "yes" and "no")
+	TrueLiteral  string
+	FalseLiteral string
+
+	// FloatPrecision is the number of digits after the decimal point of
+	// non-integer numbers. Negative means as many digits as necessary.
+	FloatPrecision int
+
+	// DecimalSeparator replaces the decimal point of numbers.
+	// (e.g. "," for locale-specific spreadsheets)
+	DecimalSeparator string
+}
+
+// NewStandardValueFormatter returns new StandardValueFormatter which writes
+// "true" and "false", and numbers with as many digits as necessary.
+func NewStandardValueFormatter() *StandardValueFormatter {
+	return &StandardValueFormatter{
+		TrueLiteral:      "true",
+		FalseLiteral:     "false",
+		FloatPrecision:   -1,
+		DecimalSeparator: ".",
+	}
+}
+
+// FormatValue implements ValueFormatter.
+func (f *StandardValueFormatter) FormatValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case bool:
+		if v {
+			return f.TrueLiteral
+		}
+		return f.FalseLiteral
+	case json.Number:
+		return f.formatDecimal(string(v))
+	case float64:
+		return f.formatFloat(v, 64)
+	case float32:
+		return f.formatFloat(float64(v), 32)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case uint64:
+		return strconv.FormatUint(v, 10)
+	default:
+		return toString(value)
+	}
+}
+
+func (f *StandardValueFormatter) formatFloat(v float64, bitSize int) string {
+	precision := f.FloatPrecision
+	if precision < 0 {
+		precision = -1
+	}
+	return f.separate(strconv.FormatFloat(v, 'f', precision, bitSize))
+}
+
+// formatDecimal formats the number literal exactly, expanding the exponent.
+func (f *StandardValueFormatter) formatDecimal(s string) string {
+	mantissa, exp := s, 0
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		n, err := strconv.Atoi(s[i+1:])
+		if err != nil {
+			return s
+		}
+		mantissa, exp = s[:i], n
+	}
+	isFloat := strings.ContainsAny(s, ".eE")
+	if !isFloat {
+		return s
+	}
+
+	digits := 0
+	if i := strings.IndexByte(mantissa, '.'); i >= 0 {
+		digits = len(mantissa) - i - 1
+	}
+	digits -= exp
+	if digits < 0 {
+		digits = 0
+	}
+	if f.FloatPrecision >= 0 {
+		digits = f.FloatPrecision
+	}
+
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return s
+	}
+	formatted := r.FloatString(digits)
+	if strings.HasPrefix(formatted, "-") && strings.Trim(formatted, "-0.") == "" {
+		// rounded to zero
+		formatted = formatted[1:]
+	}
+	return f.separate(formatted)
+}
+
+func (f *StandardValueFormatter) separate(s string) string {
+	if f.DecimalSeparator == "" || f.DecimalSeparator == "." {
+		return s
+	}
+	return strings.Replace(s, ".", f.DecimalSeparator, 1)
+}
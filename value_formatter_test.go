@@ -0,0 +1,67 @@
+package json2csv
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+var testFormatValueCases = []struct {
+	value     interface{}
+	precision int
+	separator string
+	expected  string
+}{
+	{nil, -1, ".", ""},
+	{"1e3", -1, ".", "1e3"},
+	{json.Number("12"), -1, ".", "12"},
+	{json.Number("1e3"), -1, ".", "1000"},
+	{json.Number("1.5E-7"), -1, ".", "0.00000015"},
+	{json.Number("1.50e1"), -1, ".", "15.0"},
+	{json.Number("146163870.300"), -1, ".", "146163870.300"},
+	{json.Number("1.005"), 2, ".", "1.01"},
+	{json.Number("-0.001"), 2, ".", "0.00"},
+	{json.Number("12"), 2, ".", "12"},
+	{json.Number("3.25"), -1, ",", "3,25"},
+	{1000000.0, -1, ".", "1000000"},
+	{0.1, 3, ",", "0,100"},
+	{float32(0.1), -1, ".", "0.1"},
+	{int64(-5), 2, ",", "-5"},
+	{uint64(18446744073709551615), -1, ".", "18446744073709551615"},
+}
+
+func TestFormatValue(t *testing.T) {
+	for caseIndex, testCase := range testFormatValueCases {
+		f := NewStandardValueFormatter()
+		f.FloatPrecision = testCase.precision
+		f.DecimalSeparator = testCase.separator
+		actual := f.FormatValue(testCase.value)
+		if actual != testCase.expected {
+			t.Errorf("%d: Expected %v, but %v", caseIndex, testCase.expected, actual)
+		}
+	}
+}
+
+func TestWriteCSVValueFormatter(t *testing.T) {
+	results := []KeyValue{
+		{"/a": true, "/b": json.Number("2.5e2")},
+		{"/a": false, "/b": nil},
+	}
+
+	b := &bytes.Buffer{}
+	w := NewCSVWriter(b)
+	w.ValueFormatter = &StandardValueFormatter{
+		TrueLiteral:      "yes",
+		FalseLiteral:     "no",
+		FloatPrecision:   1,
+		DecimalSeparator: ",",
+	}
+	if err := w.WriteCSV(results); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "/a,/b\nyes,\"250,0\"\nno,\n"
+	if b.String() != expected {
+		t.Errorf("Expected %q, but %q", expected, b.String())
+	}
+}
@@ -0,0 +1,286 @@
+package json2csv
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/yukithm/json2csv/jsonpointer"
+)
+
+// XMLStyle represents how values of a record are laid out in XML.
+type XMLStyle uint
+
+// XML styles
+const (
+	// NestedXMLStyle nests elements along the JSON Pointer hierarchy.
+	NestedXMLStyle XMLStyle = iota
+
+	// AttributeXMLStyle flattens values into attributes of the record
+	// element, e.g. <record id="1" user.name="foo"/>.
+	AttributeXMLStyle
+)
+
+// Default element names of XMLWriter.
+const (
+	DefaultXMLRootName   = "records"
+	DefaultXMLRecordName = "record"
+	DefaultXMLItemName   = "item"
+)
+
+// XMLWriter writes records as XML elements.
+//
+// Element and attribute names are derived from JSON Pointer tokens and
+// sanitized into valid XML names. Array elements become repeated elements
+// with the name of the array. Elements of nested arrays are named ItemName.
+// Since the records are flattened, integer tokens are always treated as
+// array indexes.
+type XMLWriter struct {
+	w          io.Writer
+	Style      XMLStyle
+	RootName   string
+	RecordName string
+	ItemName   string
+	Indent     string
+
+	// Encoding is the encoding name in the XML declaration. It does not
+	// convert the output, which is always UTF-8.
+	Encoding string
+}
+
+// NewXMLWriter returns new XMLWriter with NestedXMLStyle and default names.
+func NewXMLWriter(w io.Writer) *XMLWriter {
+	return &XMLWriter{
+		w,
+		NestedXMLStyle,
+		DefaultXMLRootName,
+		DefaultXMLRecordName,
+		DefaultXMLItemName,
+		"  ",
+		"UTF-8",
+	}
+}
+
+// WriteXML writes the XML declaration and a root element which contains an
+// element per record.
+func (w *XMLWriter) WriteXML(results []KeyValue) error {
+	pts, err := sortedPointers(results)
+	if err != nil {
+		return err
+	}
+
+	if _, err := fmt.Fprintf(w.w, "<?xml version=\"1.0\" encoding=\"%s\"?>\n", w.Encoding); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w.w)
+	enc.Indent("", w.Indent)
+
+	root := xml.StartElement{Name: xml.Name{Local: XMLName(w.RootName)}}
+	if err := enc.EncodeToken(root); err != nil {
+		return err
+	}
+	for _, result := range results {
+		record := newXMLNode("")
+		for _, pointer := range pts {
+			if value, ok := result[pointer.String()]; ok {
+				record.insert(pointer, value)
+			}
+		}
+		if err := w.encodeElement(enc, XMLName(w.RecordName), record); err != nil {
+			return err
+		}
+	}
+	if err := enc.EncodeToken(root.End()); err != nil {
+		return err
+	}
+	if err := enc.Flush(); err != nil {
+		return err
+	}
+	_, err = io.WriteString(w.w, "\n")
+	return err
+}
+
+func (w *XMLWriter) encodeElement(enc *xml.Encoder, name string, node *xmlNode) error {
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+
+	if node.leaf {
+		return enc.EncodeElement(toString(node.value), start)
+	}
+	if node.isArray() {
+		if err := enc.EncodeToken(start); err != nil {
+			return err
+		}
+		if err := w.encodeArray(enc, XMLName(w.ItemName), node); err != nil {
+			return err
+		}
+		return enc.EncodeToken(start.End())
+	}
+
+	var elements []xmlElement
+	if w.Style == AttributeXMLStyle {
+		used := map[string]bool{}
+		elements = collectAttrs(node, "", &start, used)
+	} else {
+		elements = childElements(node)
+	}
+
+	if err := enc.EncodeToken(start); err != nil {
+		return err
+	}
+	for _, e := range elements {
+		if e.node.isArray() {
+			if err := w.encodeArray(enc, e.name, e.node); err != nil {
+				return err
+			}
+		} else if err := w.encodeElement(enc, e.name, e.node); err != nil {
+			return err
+		}
+	}
+	return enc.EncodeToken(start.End())
+}
+
+// encodeArray writes each element of the array as a repeated element.
+func (w *XMLWriter) encodeArray(enc *xml.Encoder, name string, node *xmlNode) error {
+	items := make([]*xmlNode, len(node.children))
+	copy(items, node.children)
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].index() < items[j].index()
+	})
+	for _, item := range items {
+		if err := w.encodeElement(enc, name, item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// xmlElement is a child element with its name.
+type xmlElement struct {
+	name string
+	node *xmlNode
+}
+
+// childElements returns the children with their element names. Names
+// colliding after sanitization get "_2", "_3", ... suffixes like attributes,
+// skipping names which other children already have.
+func childElements(node *xmlNode) []xmlElement {
+	reserved := make(map[string]bool, len(node.children))
+	for _, child := range node.children {
+		reserved[XMLName(child.token)] = true
+	}
+
+	used := make(map[string]bool, len(node.children))
+	elements := make([]xmlElement, 0, len(node.children))
+	for _, child := range node.children {
+		base := XMLName(child.token)
+		name := base
+		for n := 2; used[name] || (name != base && reserved[name]); n++ {
+			name = fmt.Sprintf("%s_%d", base, n)
+		}
+		used[name] = true
+		elements = append(elements, xmlElement{name, child})
+	}
+	return elements
+}
+
+// collectAttrs adds leaves in the object (and nested objects) as attributes
+// and returns arrays which must be written as elements.
+func collectAttrs(node *xmlNode, prefix string, start *xml.StartElement, used map[string]bool) []xmlElement {
+	var elements []xmlElement
+	for _, child := range node.children {
+		name := prefix + XMLName(child.token)
+		switch {
+		case child.leaf:
+			attr := name
+			for n := 2; used[attr]; n++ {
+				attr = fmt.Sprintf("%s_%d", name, n)
+			}
+			used[attr] = true
+			start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: attr}, Value: toString(child.value)})
+		case child.isArray():
+			elements = append(elements, xmlElement{name, child})
+		default:
+			elements = append(elements, collectAttrs(child, name+".", start, used)...)
+		}
+	}
+	return elements
+}
+
+// xmlNode is a node of the tree rebuilt from a flattened record.
+type xmlNode struct {
+	token    string
+	leaf     bool
+	value    interface{}
+	children []*xmlNode
+	lookup   map[string]*xmlNode
+}
+
+func newXMLNode(token string) *xmlNode {
+	return &xmlNode{token: token, lookup: map[string]*xmlNode{}}
+}
+
+func (n *xmlNode) insert(pointer jsonpointer.JSONPointer, value interface{}) {
+	node := n
+	for _, token := range pointer {
+		child, ok := node.lookup[string(token)]
+		if !ok {
+			child = newXMLNode(string(token))
+			node.lookup[string(token)] = child
+			node.children = append(node.children, child)
+		}
+		node = child
+	}
+	node.leaf = true
+	node.value = value
+}
+
+func (n *xmlNode) isArray() bool {
+	if n.leaf || len(n.children) == 0 {
+		return false
+	}
+	for _, child := range n.children {
+		if !jsonpointer.Token(child.token).IsIndex() {
+			return false
+		}
+	}
+	return true
+}
+
+func (n *xmlNode) index() int {
+	i, _ := strconv.Atoi(n.token)
+	return i
+}
+
+// XMLName sanitizes the string into a valid XML name (without namespace).
+// Invalid characters are replaced with '_', and '_' is prepended if the name
+// does not start with a letter or '_', or starts with "xml".
+func XMLName(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if isXMLNameChar(r) {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+	name := b.String()
+
+	if name == "" {
+		return "_"
+	}
+	first := []rune(name)[0]
+	if (first != '_' && !unicode.IsLetter(first)) || strings.HasPrefix(strings.ToLower(name), "xml") {
+		name = "_" + name
+	}
+	return name
+}
+
+func isXMLNameChar(r rune) bool {
+	return r == '_' || r == '-' || r == '.' ||
+		unicode.IsLetter(r) || unicode.IsDigit(r) ||
+		unicode.In(r, unicode.Mn, unicode.Mc)
+}
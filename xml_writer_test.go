@@ -0,0 +1,134 @@
+package json2csv
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+var testXMLNameCases = []struct {
+	name     string
+	expected string
+}{
+	{"foo", "foo"},
+	{"foo bar", "foo_bar"},
+	{"a:b/c", "a_b_c"},
+	{"0", "_0"},
+	{"-x", "_-x"},
+	{"", "_"},
+	{"xmlns", "_xmlns"},
+	{"名前", "名前"},
+}
+
+func TestXMLName(t *testing.T) {
+	for caseIndex, testCase := range testXMLNameCases {
+		actual := XMLName(testCase.name)
+		if actual != testCase.expected {
+			t.Errorf("%d: Expected %v, but %v", caseIndex, testCase.expected, actual)
+		}
+	}
+}
+
+var testXMLResults = []KeyValue{
+	{
+		"/id":             json.Number("1"),
+		"/name":           "a<b",
+		"/user/name":      "foo",
+		"/tags/0":         "x",
+		"/tags/1":         "y",
+		"/items/0/id":     json.Number("10"),
+		"/items/1/id":     json.Number("11"),
+		"/items/1/note":   nil,
+		"/matrix/0/0":     true,
+		"/first name":     "bar",
+		"/first_name":     "baz",
+		"/user/groups/0":  "admin",
+		"/user/groups/10": "dev",
+	},
+}
+
+func TestWriteXMLNested(t *testing.T) {
+	b := &bytes.Buffer{}
+	w := NewXMLWriter(b)
+	if err := w.WriteXML(testXMLResults); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `<?xml version="1.0" encoding="UTF-8"?>
+<records>
+  <record>
+    <first_name>bar</first_name>
+    <first_name_2>baz</first_name_2>
+    <id>1</id>
+    <name>a&lt;b</name>
+    <tags>x</tags>
+    <tags>y</tags>
+    <user>
+      <name>foo</name>
+      <groups>admin</groups>
+      <groups>dev</groups>
+    </user>
+    <items>
+      <id>10</id>
+    </items>
+    <items>
+      <id>11</id>
+      <note></note>
+    </items>
+    <matrix>
+      <item>true</item>
+    </matrix>
+  </record>
+</records>
+`
+	if b.String() != expected {
+		t.Errorf("Expected %v, but %v", expected, b.String())
+	}
+}
+
+func TestWriteXMLAttribute(t *testing.T) {
+	b := &bytes.Buffer{}
+	w := NewXMLWriter(b)
+	w.Style = AttributeXMLStyle
+	w.RootName = "users"
+	w.RecordName = "user"
+	if err := w.WriteXML(testXMLResults); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `<?xml version="1.0" encoding="UTF-8"?>
+<users>
+  <user first_name="bar" first_name_2="baz" id="1" name="a&lt;b" user.name="foo">
+    <tags>x</tags>
+    <tags>y</tags>
+    <user.groups>admin</user.groups>
+    <user.groups>dev</user.groups>
+    <items id="10"></items>
+    <items id="11" note=""></items>
+    <matrix>
+      <item>true</item>
+    </matrix>
+  </user>
+</users>
+`
+	if b.String() != expected {
+		t.Errorf("Expected %v, but %v", expected, b.String())
+	}
+}
+
+func TestWriteXMLNestedNames(t *testing.T) {
+	b := &bytes.Buffer{}
+	w := NewXMLWriter(b)
+	w.Indent = ""
+	results := []KeyValue{{"/a b": 1, "/a_b": 2, "/a_b_2": 3}}
+	if err := w.WriteXML(results); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `<?xml version="1.0" encoding="UTF-8"?>
+<records><record><a_b>1</a_b><a_b_3>2</a_b_3><a_b_2>3</a_b_2></record></records>
+`
+	if b.String() != expected {
+		t.Errorf("Expected %v, but %v", expected, b.String())
+	}
+}